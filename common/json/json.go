@@ -16,6 +16,7 @@ type ResponseMessage struct {
 	Data       Response        `json:"-"`
 	CRID       string          `json:"crid"`
 	ResultCode byte            `json:"result"`
+	Err        error           `json:"-"`
 }
 
 // Request sent to server and will receive a response with same crid
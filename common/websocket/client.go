@@ -2,12 +2,14 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/url"
-	"os"
-	"os/signal"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -16,142 +18,393 @@ import (
 	"github.com/gorilla/websocket"
 )
 
+// ErrDisconnected is returned to the pending requests when the connection drops and the client does not replay them
+var ErrDisconnected = errors.New("websocket: connection lost before the response")
+
+// ErrClosed is returned to the pending requests when the client is closed
+var ErrClosed = errors.New("websocket: client closed")
+
+// ErrTimeout is returned by Do when the deadline of the context expires before the response
+var ErrTimeout = errors.New("websocket: request timed out")
+
+// ErrUnexpectedResponse is returned by Do when the type of the response is unknown or its data cannot be read
+var ErrUnexpectedResponse = errors.New("websocket: unexpected response")
+
+// ResultError is returned when the server answers a request with a non zero result code
+type ResultError struct {
+	Type       string
+	ResultCode byte
+}
+
+func (err *ResultError) Error() string {
+	return fmt.Sprintf("websocket: %s failed with result code %d", err.Type, err.ResultCode)
+}
+
+type pending struct {
+	message []byte
+	channel chan *api.ResponseMessage
+}
+
 type WebSocketClient struct {
-	requests  chan []byte
-	crids     map[string]chan *api.ResponseMessage
-	id        uint32
-	seed      int64
-	increment int
-	factory   func(t string) (api.Response, bool)
+	mutex      sync.Mutex
+	connection *websocket.Conn
+	crids      map[string]*pending
+	state      State
+	closing    chan struct{}
+	once       sync.Once
+	id         uint32
+	seed       int64
+	increment  uint64
+	factory    func(t string) (api.Response, bool)
+
+	notifications func(t string) (api.Notification, bool)
+	subscriptions map[string][]*subscription
+	subscription  uint64
+
+	// Backoff is the delay policy between two connection attempts
+	Backoff Backoff
+	// Replay resends the pending requests once reconnected, otherwise they fail with ErrDisconnected
+	Replay bool
+	// OnStateChange is called every time the connection state changes
+	OnStateChange func(state State)
+	// Dialer opens the connection, websocket.DefaultDialer when nil
+	Dialer *Dialer
+	// Logger receives the logs of the client, the standard logger when nil
+	Logger *log.Logger
+	// Frames receives the raw messages sent and received, they may contain secrets and are not logged when nil
+	Frames *log.Logger
 }
 
+// Dialer is the gorilla websocket dialer, its TLSClientConfig is used for the wss connections
+type Dialer = websocket.Dialer
+
 var unique = uint32(0)
 
 // instanciates a WebSocketClient that will use the factory function to instanciate the response for a given type
 func Client(factory func(t string) (api.Response, bool)) *WebSocketClient {
 	client := &WebSocketClient{}
-	client.requests = make(chan []byte)
-	client.crids = make(map[string]chan *api.ResponseMessage)
+	client.crids = make(map[string]*pending)
+	client.subscriptions = make(map[string][]*subscription)
+	client.closing = make(chan struct{})
 	client.seed = time.Now().Unix()
-	client.increment = 0
 	client.factory = factory
+	client.Backoff = DefaultBackoff
+	client.Replay = true
 	atomic.AddUint32(&client.id, uint32(1))
 	return client
 }
 
-func send(client *WebSocketClient, bytes []byte) {
-	client.requests <- bytes
-}
-
 func receive(client *WebSocketClient, bytes []byte) {
 	// we parse the header
 	var response api.ResponseMessage
 	err := json.Unmarshal(bytes, &response)
 	if err != nil {
-		log.Println("Unmarshal message header error:", err)
+		client.logger().Println("Unmarshal message header error:", err)
 		return
 	}
 
-	// we get the data
+	// it is a notification
+	if response.CRID == "" {
+		notify(client, &response)
+		return
+	}
+
+	// we get the data, the requester fails when we cannot read it rather than waiting for its deadline
 	data, success := client.factory(response.Type)
 	if !success {
-		log.Println("Unknow response type :", response.Type)
+		client.logger().Println("Unknow response type :", response.Type)
+		response.Err = fmt.Errorf("%w : unknown type %s", ErrUnexpectedResponse, response.Type)
+	} else if err = json.Unmarshal(response.RawData, data); err != nil {
+		client.logger().Println("Unmarshal message data error:", err)
+		response.Err = fmt.Errorf("%w : %s : %v", ErrUnexpectedResponse, response.Type, err)
 	} else {
-		err = json.Unmarshal(response.RawData, data)
-		if err != nil {
-			log.Println("Unmarshal message data error:", err)
-			return
-		}
 		response.Data = data
 	}
 
-	// TODO
-	// it is a notification
-	if response.CRID == "" {
-		return
-	}
-
 	// we notify the requester channel
-	channel, ok := client.crids[response.CRID]
+	client.mutex.Lock()
+	request, ok := client.crids[response.CRID]
+	delete(client.crids, response.CRID)
+	client.mutex.Unlock()
+
 	if !ok {
-		log.Println("unknown CRID :", response.CRID)
+		client.logger().Println("unknown CRID :", response.CRID)
 	} else {
-		channel <- &response
+		request.channel <- &response
 	}
 }
 
-// Connect to the server and blocks the thread while the connection is opened
+// Connect to the server and blocks the thread until the client is closed
+// the uri is either host:port or a ws:// or wss:// url
+// when the connection drops, the client reconnects with the Backoff delay
 func (client *WebSocketClient) Connect(uri string) {
-	interrupt := make(chan os.Signal, 1)
-	signal.Notify(interrupt, os.Interrupt)
-
 	u := url.URL{Scheme: "ws", Host: uri, Path: ""}
-	log.Printf("connecting to %s", u.String())
+	if strings.Contains(uri, "://") {
+		parsed, err := url.Parse(uri)
+		if err != nil {
+			client.logger().Println("invalid uri :", err)
+			client.Close()
+			client.closed()
+			return
+		}
+		u = *parsed
+	}
+
+	attempt := 0
+	for {
+		client.setState(Connecting)
+		client.logger().Printf("connecting to %s", u.String())
+
+		connected, err := client.run(u.String())
+		if connected {
+			attempt = 0
+		}
+		client.disconnected()
+
+		if client.isClosing() {
+			break
+		}
+
+		delay := client.Backoff.Duration(attempt)
+		attempt++
+		client.logger().Printf("connection lost (%v), reconnecting in %v", err, delay)
+
+		select {
+		case <-time.After(delay):
+		case <-client.closing:
+		}
+
+		if client.isClosing() {
+			break
+		}
+	}
+
+	client.closed()
+}
+
+// run opens the connection and blocks until it is lost or the client is closed
+func (client *WebSocketClient) run(uri string) (bool, error) {
+	dialer := client.Dialer
+	if dialer == nil {
+		dialer = websocket.DefaultDialer
+	}
 
-	c, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
+	c, _, err := dialer.Dial(uri, nil)
 	if err != nil {
-		log.Fatal("dial:", err)
+		return false, err
 	}
 	defer c.Close()
 
-	done := make(chan struct{})
+	done := make(chan error, 1)
 
 	go func() {
-		defer close(done)
 		for {
 			_, message, err := c.ReadMessage()
 			if err != nil {
-				log.Println("error on read:", err)
+				client.logger().Println("error on read:", err)
+				done <- err
 				return
 			}
-			log.Printf("recv: %s", message)
+			if client.Frames != nil {
+				client.Frames.Printf("recv: %s", message)
+			}
 			receive(client, message)
 		}
 	}()
 
-	for {
+	// the requests that are still pending are sent on the new connection
+	client.mutex.Lock()
+	client.connection = c
+	for _, request := range client.crids {
+		client.write(request.message)
+	}
+	client.mutex.Unlock()
+
+	client.setState(Connected)
+
+	select {
+	case err := <-done:
+		return true, err
+	case <-client.closing:
+		// Cleanly close the connection by sending a close message and then
+		// waiting (with timeout) for the server to close the connection.
+		client.mutex.Lock()
+		err := c.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+		client.mutex.Unlock()
+		if err != nil {
+			client.logger().Println("write close:", err)
+			return true, err
+		}
 		select {
 		case <-done:
-			return
-		case request := <-client.requests:
-			err := c.WriteMessage(websocket.TextMessage, request)
-			if err != nil {
-				log.Println("error on write:", err)
-				return
-			}
-			log.Println("write:", string(request))
-		case <-interrupt:
-			log.Println("interrupt")
-
-			// Cleanly close the connection by sending a close message and then
-			// waiting (with timeout) for the server to close the connection.
-			err := c.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
-			if err != nil {
-				log.Println("write close:", err)
-				return
-			}
-			select {
-			case <-done:
-			case <-time.After(time.Second):
-			}
-			return
+		case <-time.After(time.Second):
 		}
+		return true, nil
+	}
+}
+
+// disconnected forgets the lost connection and fails the pending requests unless we replay them
+func (client *WebSocketClient) disconnected() {
+	client.mutex.Lock()
+	client.connection = nil
+	client.mutex.Unlock()
+
+	if client.isClosing() {
+		return
+	}
+
+	client.setState(Disconnected)
+
+	if !client.Replay {
+		client.fail(ErrDisconnected)
+	}
+}
+
+// fail notifies every pending request with the error
+func (client *WebSocketClient) fail(err error) {
+	client.mutex.Lock()
+	crids := client.crids
+	client.crids = make(map[string]*pending)
+	client.mutex.Unlock()
+
+	for crid, request := range crids {
+		request.channel <- &api.ResponseMessage{CRID: crid, Err: err}
+	}
+}
+
+// closed fails the pending requests with ErrClosed and forgets the subscriptions, they are kept across the reconnections
+func (client *WebSocketClient) closed() {
+	client.fail(ErrClosed)
+	client.mutex.Lock()
+	client.subscriptions = make(map[string][]*subscription)
+	client.mutex.Unlock()
+	client.setState(Closed)
+}
+
+// write sends the message on the current connection, the caller must hold the mutex
+func (client *WebSocketClient) write(message []byte) {
+	if client.connection == nil {
+		// the message will be sent once connected
+		return
+	}
+
+	err := client.connection.WriteMessage(websocket.TextMessage, message)
+	if err != nil {
+		client.logger().Println("error on write:", err)
+		// the read loop will notice the connection is broken
+		client.connection.Close()
+		return
+	}
+	if client.Frames != nil {
+		client.Frames.Println("write:", string(message))
+	}
+}
+
+// Close stops the client, the pending requests fail with ErrClosed
+func (client *WebSocketClient) Close() {
+	client.once.Do(func() {
+		close(client.closing)
+	})
+}
+
+func (client *WebSocketClient) logger() *log.Logger {
+	if client.Logger == nil {
+		return log.Default()
+	}
+	return client.Logger
+}
+
+func (client *WebSocketClient) isClosing() bool {
+	select {
+	case <-client.closing:
+		return true
+	default:
+		return false
+	}
+}
+
+// State returns the current state of the connection
+func (client *WebSocketClient) State() State {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	return client.state
+}
+
+func (client *WebSocketClient) setState(state State) {
+	client.mutex.Lock()
+	changed := client.state != state
+	client.state = state
+	client.mutex.Unlock()
+
+	if changed && client.OnStateChange != nil {
+		client.OnStateChange(state)
 	}
 }
 
-// TODO make thread safe
 func (client *WebSocketClient) crid() string {
-	crid := fmt.Sprintf("%d-%d-%d", client.seed, client.id, client.increment)
-	client.increment++
-	return crid
+	increment := atomic.AddUint64(&client.increment, 1) - 1
+	return fmt.Sprintf("%d-%d-%d", client.seed, client.id, increment)
+}
+
+// Do sends the request and waits for the response until the context is done
+// it is safe to call from several goroutines, a non zero result code is returned as a *ResultError
+func (client *WebSocketClient) Do(ctx context.Context, request api.Request, t string) (api.Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	message := client.RequestMessage(request, t)
+	channel := client.Request(message)
+
+	select {
+	case response := <-channel:
+		if response.Err != nil {
+			return nil, response.Err
+		}
+		if response.ResultCode != 0 {
+			return response.Data, &ResultError{Type: t, ResultCode: response.ResultCode}
+		}
+		return response.Data, nil
+	case <-ctx.Done():
+		client.forget(message.CRID)
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, ErrTimeout
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// forget stops waiting for the response of the request
+func (client *WebSocketClient) forget(crid string) {
+	client.mutex.Lock()
+	delete(client.crids, crid)
+	client.mutex.Unlock()
+}
+
+// waiting returns the number of requests waiting for a response
+func (client *WebSocketClient) waiting() int {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	return len(client.crids)
 }
 
 // Request is serialized and sent to the server
+// when the client is not connected, it will be sent once the connection is established
 func (client *WebSocketClient) Request(request *api.RequestMessage) chan *api.ResponseMessage {
-	channel := make(chan *api.ResponseMessage)
+	// the channel is buffered so the read loop never waits for the requester
+	channel := make(chan *api.ResponseMessage, 1)
 	marshaled, _ := json.Marshal(request)
-	send(client, marshaled)
-	client.crids[request.CRID] = channel
+
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	if client.isClosing() {
+		channel <- &api.ResponseMessage{CRID: request.CRID, Err: ErrClosed}
+		return channel
+	}
+
+	client.crids[request.CRID] = &pending{message: marshaled, channel: channel}
+	client.write(marshaled)
 	return channel
 }
 
@@ -0,0 +1,422 @@
+package websocket
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	api "republicofminer-client-go/common/json"
+	"republicofminer-client-go/common/websocket/websockettest"
+)
+
+type echo struct {
+	Text string
+}
+
+type ping struct {
+	Text string
+}
+
+func notifications(t string) (api.Notification, bool) {
+	if t == "PingNotification" {
+		return &ping{}, true
+	}
+	return nil, false
+}
+
+func factory(t string) (api.Response, bool) {
+	if t == "EchoResponse" {
+		return &echo{}, true
+	}
+	return nil, false
+}
+
+var fast = Backoff{Min: 10 * time.Millisecond, Max: 50 * time.Millisecond, Factor: 2}
+
+// server answers the echo requests, drop decides for each connection if it drops the request instead
+// the texts "ignore" and "fail" are never answered or answered with a result code, "notify" pushes a notification first
+// "unknown" and "malformed" are answered with a type the client does not know or data it cannot read
+func server(t *testing.T, drop func(connection int) bool) *httptest.Server {
+	s := unstarted(t, drop)
+	s.Start()
+	return s
+}
+
+func unstarted(t *testing.T, drop func(connection int) bool) *httptest.Server {
+	return websockettest.Unstarted(t, map[string]websockettest.Responder{
+		"EchoRequest": func(data json.RawMessage) []websockettest.Message {
+			var request echo
+			json.Unmarshal(data, &request)
+
+			switch request.Text {
+			case "ignore":
+				return nil
+			case "fail":
+				return websockettest.Response("EchoResponse", request, 3)
+			case "unknown":
+				return websockettest.Response("UnknownResponse", request, 0)
+			case "malformed":
+				return websockettest.Response("EchoResponse", map[string]int{"Text": 1}, 0)
+			case "notify":
+				return append([]websockettest.Message{websockettest.Message{Type: "PingNotification", Data: ping{"pushed"}, Notification: true}}, websockettest.Response("EchoResponse", request, 0)...)
+			}
+			return websockettest.Response("EchoResponse", request, 0)
+		},
+	}, drop)
+}
+
+func address(server *httptest.Server) string {
+	return websockettest.Address(server)
+}
+
+func listen(t *testing.T, uri string) net.Listener {
+	listener, err := net.Listen("tcp", uri)
+	if err != nil {
+		t.Fatal("listen:", err)
+	}
+	return listener
+}
+
+func wait(t *testing.T, channel chan *api.ResponseMessage) *api.ResponseMessage {
+	select {
+	case response := <-channel:
+		return response
+	case <-time.After(5 * time.Second):
+		t.Fatal("no response received")
+		return nil
+	}
+}
+
+func TestReplayAfterReconnect(t *testing.T) {
+	// the first connection is dropped as soon as it receives the request
+	s := server(t, func(connection int) bool { return connection == 0 })
+	defer s.Close()
+
+	var mutex sync.Mutex
+	var states []State
+
+	client := Client(factory)
+	client.Backoff = fast
+	client.OnStateChange = func(state State) {
+		mutex.Lock()
+		states = append(states, state)
+		mutex.Unlock()
+	}
+	go client.Connect(address(s))
+	defer client.Close()
+
+	response := wait(t, client.Request(client.RequestMessage(&echo{"hello"}, "EchoRequest")))
+	if response.Err != nil {
+		t.Fatal("the request should have been replayed :", response.Err)
+	}
+	if response.Data.(*echo).Text != "hello" {
+		t.Fatal("unexpected response :", response.Data)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	expected := []State{Connecting, Connected, Disconnected, Connecting, Connected}
+	if len(states) < len(expected) {
+		t.Fatal("expected :", expected, "actual", states)
+	}
+	for index, state := range expected {
+		if states[index] != state {
+			t.Fatal("expected :", expected, "actual", states)
+		}
+	}
+}
+
+func TestFailWithoutReplay(t *testing.T) {
+	s := server(t, func(connection int) bool { return true })
+	defer s.Close()
+
+	client := Client(factory)
+	client.Backoff = fast
+	client.Replay = false
+	go client.Connect(address(s))
+	defer client.Close()
+
+	response := wait(t, client.Request(client.RequestMessage(&echo{"hello"}, "EchoRequest")))
+	if response.Err != ErrDisconnected {
+		t.Fatal("expected :", ErrDisconnected, "actual", response.Err)
+	}
+}
+
+func TestReconnectWhenServerIsDown(t *testing.T) {
+	s := server(t, func(connection int) bool { return false })
+	uri := address(s)
+	// nothing listens on the address until the server restarts
+	s.Close()
+
+	client := Client(factory)
+	client.Backoff = fast
+	go client.Connect(uri)
+	defer client.Close()
+
+	channel := client.Request(client.RequestMessage(&echo{"hello"}, "EchoRequest"))
+	time.Sleep(100 * time.Millisecond)
+
+	restarted := unstarted(t, func(connection int) bool { return false })
+	restarted.Listener.Close()
+	restarted.Listener = listen(t, uri)
+	restarted.Start()
+	defer restarted.Close()
+
+	response := wait(t, channel)
+	if response.Err != nil {
+		t.Fatal("the request should have been sent once connected :", response.Err)
+	}
+}
+
+func TestClose(t *testing.T) {
+	s := server(t, func(connection int) bool { return true })
+	defer s.Close()
+
+	client := Client(factory)
+	client.Backoff = fast
+	stopped := make(chan struct{})
+	go func() {
+		client.Connect(address(s))
+		close(stopped)
+	}()
+
+	channel := client.Request(client.RequestMessage(&echo{"hello"}, "EchoRequest"))
+	client.Close()
+
+	response := wait(t, channel)
+	if response.Err != ErrClosed {
+		t.Fatal("expected :", ErrClosed, "actual", response.Err)
+	}
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Connect should return once the client is closed")
+	}
+
+	if client.State() != Closed {
+		t.Fatal("expected :", Closed, "actual", client.State())
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	backoff := Backoff{Min: time.Second, Max: 10 * time.Second, Factor: 2, Jitter: 0.5}
+	for attempt := 0; attempt < 10; attempt++ {
+		expected := time.Second << uint(attempt)
+		if expected > backoff.Max {
+			expected = backoff.Max
+		}
+		actual := backoff.Duration(attempt)
+		if actual < expected/2 || actual > expected*3/2 {
+			t.Fatal("attempt", attempt, "expected around :", expected, "actual", actual)
+		}
+	}
+}
+
+func TestDoConcurrent(t *testing.T) {
+	s := server(t, func(connection int) bool { return false })
+	defer s.Close()
+
+	client := Client(factory)
+	go client.Connect(address(s))
+	defer client.Close()
+
+	var group sync.WaitGroup
+	for index := 0; index < 50; index++ {
+		group.Add(1)
+		go func(index int) {
+			defer group.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			expected := fmt.Sprint("hello ", index)
+			response, err := client.Do(ctx, &echo{expected}, "EchoRequest")
+			if err != nil {
+				t.Error("request failed :", err)
+				return
+			}
+			if actual := response.(*echo).Text; actual != expected {
+				t.Error("expected :", expected, "actual", actual)
+			}
+		}(index)
+	}
+	group.Wait()
+
+	if client.waiting() != 0 {
+		t.Fatal("the finished requests should be removed, remaining", client.waiting())
+	}
+}
+
+func TestDoDeadline(t *testing.T) {
+	s := server(t, func(connection int) bool { return false })
+	defer s.Close()
+
+	client := Client(factory)
+	go client.Connect(address(s))
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err := client.Do(ctx, &echo{"ignore"}, "EchoRequest")
+	if err != ErrTimeout {
+		t.Fatal("expected :", ErrTimeout, "actual", err)
+	}
+
+	if client.waiting() != 0 {
+		t.Fatal("the expired request should be removed")
+	}
+}
+
+func TestDoResultCode(t *testing.T) {
+	s := server(t, func(connection int) bool { return false })
+	defer s.Close()
+
+	client := Client(factory)
+	go client.Connect(address(s))
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := client.Do(ctx, &echo{"fail"}, "EchoRequest")
+	result, ok := err.(*ResultError)
+	if !ok || result.ResultCode != 3 || result.Type != "EchoRequest" {
+		t.Fatal("expected a result error, actual", err)
+	}
+}
+
+func TestDoUnexpectedResponse(t *testing.T) {
+	s := server(t, func(connection int) bool { return false })
+	defer s.Close()
+
+	client := Client(factory)
+	client.Logger = log.New(io.Discard, "", 0)
+	go client.Connect(address(s))
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// the request fails at once instead of waiting for the deadline
+	for _, text := range []string{"unknown", "malformed"} {
+		if _, err := client.Do(ctx, &echo{text}, "EchoRequest"); !errors.Is(err, ErrUnexpectedResponse) {
+			t.Fatal(text, "expected :", ErrUnexpectedResponse, "actual", err)
+		}
+	}
+	if client.waiting() != 0 {
+		t.Fatal("the failed requests should be removed, remaining", client.waiting())
+	}
+}
+
+func TestFrames(t *testing.T) {
+	s := server(t, func(connection int) bool { return false })
+	defer s.Close()
+
+	// the frames may contain secrets, they only go to the frames logger
+	var logs, frames bytes.Buffer
+	client := Client(factory)
+	client.Logger = log.New(&logs, "", 0)
+	client.Frames = log.New(&frames, "", 0)
+	go client.Connect(address(s))
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := client.Do(ctx, &echo{"secret"}, "EchoRequest"); err != nil {
+		t.Fatal("request failed :", err)
+	}
+	if strings.Contains(logs.String(), "secret") {
+		t.Fatal("expected no frame in the logs, actual", logs.String())
+	}
+	if !strings.Contains(frames.String(), "write:") || !strings.Contains(frames.String(), "recv:") {
+		t.Fatal("expected the frames, actual", frames.String())
+	}
+}
+
+func TestNotifications(t *testing.T) {
+	s := server(t, func(connection int) bool { return false })
+	defer s.Close()
+
+	client := Client(factory)
+	client.NotificationFactory(notifications)
+	go client.Connect(address(s))
+	defer client.Close()
+
+	handled := make(chan *ping, 2)
+	unsubscribe := client.Subscribe("PingNotification", func(notification api.Notification) {
+		handled <- notification.(*ping)
+	})
+	channel, _ := client.SubscribeChannel("PingNotification", 2)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// the notification is pushed before the response
+	if _, err := client.Do(ctx, &echo{"notify"}, "EchoRequest"); err != nil {
+		t.Fatal("request failed :", err)
+	}
+
+	if actual := (<-handled).Text; actual != "pushed" {
+		t.Fatal("expected : pushed actual", actual)
+	}
+	if actual := (<-channel).(*ping).Text; actual != "pushed" {
+		t.Fatal("expected : pushed actual", actual)
+	}
+
+	unsubscribe()
+	if _, err := client.Do(ctx, &echo{"notify"}, "EchoRequest"); err != nil {
+		t.Fatal("request failed :", err)
+	}
+
+	<-channel
+	select {
+	case <-handled:
+		t.Fatal("the handler should be unsubscribed")
+	default:
+	}
+}
+
+func TestSubscriptionsKeptAcrossReconnections(t *testing.T) {
+	// the first connection is dropped as soon as it receives a request
+	s := server(t, func(connection int) bool { return connection == 0 })
+	defer s.Close()
+
+	client := Client(factory)
+	client.Backoff = fast
+	client.Replay = false
+	client.NotificationFactory(notifications)
+	go client.Connect(address(s))
+	defer client.Close()
+
+	channel, _ := client.SubscribeChannel("PingNotification", 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := client.Do(ctx, &echo{"hello"}, "EchoRequest"); err != ErrDisconnected {
+		t.Fatal("expected :", ErrDisconnected, "actual", err)
+	}
+	if _, err := client.Do(ctx, &echo{"notify"}, "EchoRequest"); err != nil {
+		t.Fatal("request failed :", err)
+	}
+
+	select {
+	case notification := <-channel:
+		if actual := notification.(*ping).Text; actual != "pushed" {
+			t.Fatal("expected : pushed actual", actual)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the subscription should survive the reconnection")
+	}
+}
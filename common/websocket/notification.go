@@ -0,0 +1,89 @@
+package websocket
+
+import (
+	"encoding/json"
+
+	api "republicofminer-client-go/common/json"
+)
+
+type subscription struct {
+	id      uint64
+	handler func(notification api.Notification)
+}
+
+// NotificationFactory sets the function that will instanciate the notification for a given type
+func (client *WebSocketClient) NotificationFactory(factory func(t string) (api.Notification, bool)) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	client.notifications = factory
+}
+
+// Subscribe calls the handler with the decoded notification every time the server pushes a notification of the given type
+// the handlers are called from the read loop and should not block, it returns the function to unsubscribe
+func (client *WebSocketClient) Subscribe(t string, handler func(notification api.Notification)) func() {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	client.subscription++
+	id := client.subscription
+	client.subscriptions[t] = append(client.subscriptions[t], &subscription{id: id, handler: handler})
+
+	return func() {
+		client.mutex.Lock()
+		defer client.mutex.Unlock()
+
+		subscriptions := client.subscriptions[t]
+		for index, s := range subscriptions {
+			if s.id == id {
+				client.subscriptions[t] = append(subscriptions[:index:index], subscriptions[index+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// SubscribeChannel sends the notifications of the given type to a channel buffered with the given size
+// the notifications are dropped when the channel is full, the channel is not closed when unsubscribing
+func (client *WebSocketClient) SubscribeChannel(t string, size int) (<-chan api.Notification, func()) {
+	channel := make(chan api.Notification, size)
+	unsubscribe := client.Subscribe(t, func(notification api.Notification) {
+		select {
+		case channel <- notification:
+		default:
+			client.logger().Println("notification dropped, the channel is full :", t)
+		}
+	})
+	return channel, unsubscribe
+}
+
+func notify(client *WebSocketClient, message *api.ResponseMessage) {
+	client.mutex.Lock()
+	factory := client.notifications
+	subscriptions := client.subscriptions[message.Type]
+	client.mutex.Unlock()
+
+	if len(subscriptions) == 0 {
+		return
+	}
+
+	if factory == nil {
+		client.logger().Println("Unknow notification type :", message.Type)
+		return
+	}
+
+	notification, success := factory(message.Type)
+	if !success {
+		client.logger().Println("Unknow notification type :", message.Type)
+		return
+	}
+
+	err := json.Unmarshal(message.RawData, notification)
+	if err != nil {
+		client.logger().Println("Unmarshal notification data error:", err)
+		return
+	}
+
+	for _, s := range subscriptions {
+		s.handler(notification)
+	}
+}
@@ -0,0 +1,52 @@
+package websocket
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// State of the connection to the server
+type State int
+
+const (
+	Disconnected State = iota
+	Connecting
+	Connected
+	Closed
+)
+
+func (state State) String() string {
+	switch state {
+	case Disconnected:
+		return "disconnected"
+	case Connecting:
+		return "connecting"
+	case Connected:
+		return "connected"
+	case Closed:
+		return "closed"
+	}
+	return "unknown"
+}
+
+// Backoff computes an exponential delay between two connection attempts
+type Backoff struct {
+	Min    time.Duration
+	Max    time.Duration
+	Factor float64
+	// Jitter is the random part of the delay, 0.2 means +/- 20%
+	Jitter float64
+}
+
+var DefaultBackoff = Backoff{Min: 500 * time.Millisecond, Max: 30 * time.Second, Factor: 2, Jitter: 0.2}
+
+// Duration returns the delay to wait before the given attempt, starting at 0
+func (backoff Backoff) Duration(attempt int) time.Duration {
+	delay := float64(backoff.Min) * math.Pow(backoff.Factor, float64(attempt))
+	if delay > float64(backoff.Max) {
+		delay = float64(backoff.Max)
+	}
+	delay += delay * backoff.Jitter * (rand.Float64()*2 - 1)
+	return time.Duration(delay)
+}
@@ -0,0 +1,99 @@
+// The websockettest package serves stand-in websocket servers for the tests of the clients.
+// It imports testing on purpose to report the failures of the stand-ins, like net/http/httptest it is only imported by the _test.go files.
+package websockettest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// Message is sent by the stand-in in answer to a request
+type Message struct {
+	Type   string
+	Data   interface{}
+	Result byte
+	// Notification sends the message without the crid of the request
+	Notification bool
+}
+
+// Responder answers the data of a request, no message leaves the request unanswered
+type Responder func(data json.RawMessage) []Message
+
+// Respond is the responder answering every request with the same response
+func Respond(typ string, data interface{}, result byte) Responder {
+	return func(json.RawMessage) []Message { return Response(typ, data, result) }
+}
+
+// Response is the single response of a request
+func Response(typ string, data interface{}, result byte) []Message {
+	return []Message{Message{Type: typ, Data: data, Result: result}}
+}
+
+// Server answers each request with the responder of its type, the requests of the other types are not answered
+func Server(t testing.TB, responders map[string]Responder) *httptest.Server {
+	server := Unstarted(t, responders, nil)
+	server.Start()
+	return server
+}
+
+// Unstarted is the Server to start later, drop tells for each connection, counted from 0, if it is closed when it receives a request
+func Unstarted(t testing.TB, responders map[string]Responder, drop func(connection int) bool) *httptest.Server {
+	var mutex sync.Mutex
+	connections := 0
+	upgrader := websocket.Upgrader{}
+
+	return httptest.NewUnstartedServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		c, err := upgrader.Upgrade(writer, request, nil)
+		if err != nil {
+			t.Error("upgrade:", err)
+			return
+		}
+		defer c.Close()
+
+		mutex.Lock()
+		connection := connections
+		connections++
+		mutex.Unlock()
+
+		for {
+			_, message, err := c.ReadMessage()
+			if err != nil {
+				return
+			}
+			if drop != nil && drop(connection) {
+				return
+			}
+
+			var request struct {
+				Type string
+				Data json.RawMessage
+				CRID string
+			}
+			json.Unmarshal(message, &request)
+
+			respond, ok := responders[request.Type]
+			if !ok {
+				continue
+			}
+			for _, answer := range respond(request.Data) {
+				crid := request.CRID
+				if answer.Notification {
+					crid = ""
+				}
+				response, _ := json.Marshal(map[string]interface{}{"type": answer.Type, "data": answer.Data, "crid": crid, "result": answer.Result})
+				c.WriteMessage(websocket.TextMessage, response)
+			}
+		}
+	}))
+}
+
+// Address returns the host and port of the server
+func Address(server *httptest.Server) string {
+	return strings.TrimPrefix(server.URL, "http://")
+}
@@ -24,6 +24,24 @@ var (
 
 var errInvalidPubkey = errors.New("invalid secp256k1 public key")
 
+// ValidSignatureValues tells if r and s are between 1 and the order of the curve
+func ValidSignatureValues(r *big.Int, s *big.Int) bool {
+	return r.Cmp(one) >= 0 && r.Cmp(secp256k1N) < 0 && s.Cmp(one) >= 0 && s.Cmp(secp256k1N) < 0
+}
+
+// IsLowS tells if s is in the lower half of the order of the curve, the canonical form of a signature
+func IsLowS(s *big.Int) bool {
+	return s.Cmp(secp256k1halfN) <= 0
+}
+
+// NormalizeS returns the low s of the two values n - s and s that verify the same signature
+func NormalizeS(s *big.Int) *big.Int {
+	if IsLowS(s) {
+		return s
+	}
+	return new(big.Int).Sub(secp256k1N, s)
+}
+
 type Hash256 []byte
 
 func (hash Hash256) ToBase64() string {
@@ -3,7 +3,7 @@ package api
 import (
 	"encoding/json"
 	"errors"
-	"log"
+	"fmt"
 	api "republicofminer-client-go/common/json"
 	"republicofminer-client-go/protocol"
 )
@@ -58,11 +58,11 @@ type HashLock struct {
 	SecretHash SecretHash
 }
 
-type SecretHashType byte
+type SecretHashType = protocol.SecretHashType
 
 const (
-	SHA3   SecretHashType = 0
-	SHA256 SecretHashType = 1
+	SHA3   = protocol.SHA3
+	SHA256 = protocol.SHA256
 )
 
 type SecretHash struct {
@@ -80,6 +80,34 @@ type MultiSignature struct {
 	Required int32
 }
 
+type TimeLock struct {
+	Address   string
+	Timestamp int64
+}
+
+type VendingMachine struct {
+	Address     string
+	Owner       string
+	CurrencyIn  string
+	CurrencyOut string
+	Rate        float64
+}
+
+type LimitOrder struct {
+	Address       string
+	Owner         string
+	BaseCurrency  string
+	QuoteCurrency string
+	IsBid         bool
+	Price         float64
+	Quantity      float64
+}
+
+type DelegatedAccount struct {
+	Address string
+	Owner   string
+}
+
 // GetLedgerRequest ...
 type GetLedgerRequest struct {
 	Height *int64 `json:",omitempty"`
@@ -139,6 +167,28 @@ func CreateResponse(t string) (api.Response, bool) {
 	return nil, false
 }
 
+// LedgerNotification is pushed by the explorer when a new ledger is closed
+type LedgerNotification struct {
+	Ledger Ledger
+}
+
+// AccountNotification is pushed by the explorer when the balance or the declaration of an account changes
+type AccountNotification struct {
+	Address     string
+	Balance     map[string]float64
+	Declaration *TxDeclaration
+}
+
+func CreateNotification(t string) (api.Notification, bool) {
+	switch t {
+	case "LedgerNotification":
+		return &LedgerNotification{}, true
+	case "AccountNotification":
+		return &AccountNotification{}, true
+	}
+	return nil, false
+}
+
 func (declaration *TxDeclaration) UnmarshalJSON(bytes []byte) error {
 	var tmp struct {
 		Type protocol.DeclarationType
@@ -152,72 +202,79 @@ func (declaration *TxDeclaration) UnmarshalJSON(bytes []byte) error {
 	declaration.Type = tmp.Type
 	declaration.Declaration, err = CreateDeclaration(tmp.Type, bytes)
 
-	return nil
+	return err
 }
 
 func CreateDeclaration(t protocol.DeclarationType, bytes []byte) (interface{}, error) {
+	var tmp interface{}
 	switch t {
-	case protocol.TxHashLock:
-		var tmp = &HashLock{}
-		err := json.Unmarshal(bytes, tmp)
-		if err != nil {
-			return nil, err
-		}
-		return tmp, nil
 	case protocol.TxMultiSignature:
-		var tmp = &MultiSignature{}
-		err := json.Unmarshal(bytes, tmp)
-		if err != nil {
-			return nil, err
-		}
-		return tmp, nil
+		tmp = &MultiSignature{}
+	case protocol.TxHashLock:
+		tmp = &HashLock{}
 	case protocol.TxSecret:
-		var tmp = &SecretRevelation{}
-		err := json.Unmarshal(bytes, tmp)
-		if err != nil {
-			return nil, err
-		}
-		return tmp, nil
+		tmp = &SecretRevelation{}
+	case protocol.TxTimeLock:
+		tmp = &TimeLock{}
+	case protocol.TxVendingMachine:
+		tmp = &VendingMachine{}
+	case protocol.TxLimitOrder:
+		tmp = &LimitOrder{}
+	case protocol.TxDelegatedAccount:
+		tmp = &DelegatedAccount{}
+	default:
+		return nil, errors.New("Unknow declaration")
+	}
+
+	err := json.Unmarshal(bytes, tmp)
+	if err != nil {
+		return nil, err
 	}
-	return nil, errors.New("Unknow declaration")
+	return tmp, nil
 }
 
 func (declaration *TxDeclaration) MarshalJSON() ([]byte, error) {
 
+	// the type is serialized along with the fields of the declaration
 	var d interface{}
-	switch declaration.Type {
-	case protocol.TxSecret:
-		var tmp struct {
+	switch v := declaration.Declaration.(type) {
+	case *SecretRevelation:
+		d = struct {
 			Type protocol.DeclarationType
-			SecretRevelation
-		}
-		tmp.Type = declaration.Type
-		tmp.SecretRevelation.Secret = declaration.Declaration.(*SecretRevelation).Secret
-		d = tmp
-	case protocol.TxMultiSignature:
-		var tmp struct {
+			*SecretRevelation
+		}{declaration.Type, v}
+	case *MultiSignature:
+		d = struct {
 			Type protocol.DeclarationType
-			MultiSignature
-		}
-		tmp.Type = declaration.Type
-		multi := declaration.Declaration.(*MultiSignature)
-		tmp.MultiSignature.Address = multi.Address
-		tmp.MultiSignature.Required = multi.Required
-		tmp.MultiSignature.Signers = multi.Signers
-		d = tmp
-	case protocol.TxHashLock:
-		var tmp struct {
+			*MultiSignature
+		}{declaration.Type, v}
+	case *HashLock:
+		d = struct {
+			Type protocol.DeclarationType
+			*HashLock
+		}{declaration.Type, v}
+	case *TimeLock:
+		d = struct {
+			Type protocol.DeclarationType
+			*TimeLock
+		}{declaration.Type, v}
+	case *VendingMachine:
+		d = struct {
+			Type protocol.DeclarationType
+			*VendingMachine
+		}{declaration.Type, v}
+	case *LimitOrder:
+		d = struct {
+			Type protocol.DeclarationType
+			*LimitOrder
+		}{declaration.Type, v}
+	case *DelegatedAccount:
+		d = struct {
 			Type protocol.DeclarationType
-			HashLock
-		}
-		tmp.Type = declaration.Type
-		hashlock := declaration.Declaration.(*HashLock)
-		tmp.HashLock.Address = hashlock.Address
-		tmp.HashLock.SecretHash = hashlock.SecretHash
-		d = tmp
+			*DelegatedAccount
+		}{declaration.Type, v}
 	default:
-		log.Printf("MarshalJSON Unkown type : %d\n", declaration.Type)
-		panic(0)
+		return nil, fmt.Errorf("MarshalJSON Unkown declaration : %d", declaration.Type)
 	}
 
 	return json.Marshal(&d)
@@ -0,0 +1,38 @@
+package explorer
+
+import (
+	"errors"
+	"fmt"
+	"republicofminer-client-go/common/websocket"
+)
+
+var (
+	// ErrNotFound is returned when the explorer does not know the requested transaction, ledger or account
+	ErrNotFound = errors.New("explorer: not found")
+	// ErrRejected is returned when the explorer refuses the transaction, the error is a *RejectedError
+	ErrRejected = errors.New("explorer: transaction rejected")
+	// ErrTimeout is returned when the explorer does not answer in time
+	ErrTimeout = websocket.ErrTimeout
+	// ErrUnexpectedResponse is returned when the explorer answers with an unknown type or data that cannot be read
+	ErrUnexpectedResponse = websocket.ErrUnexpectedResponse
+)
+
+// ServerError is returned when the explorer answers with a non zero result code
+type ServerError = websocket.ResultError
+
+// RejectedError is returned when the explorer answers a SendTransactionRequest with a non zero result code
+type RejectedError struct {
+	*ServerError
+}
+
+func (err *RejectedError) Error() string {
+	return fmt.Sprintf("explorer: transaction rejected with result code %d", err.ResultCode)
+}
+
+func (err *RejectedError) Unwrap() error {
+	return err.ServerError
+}
+
+func (err *RejectedError) Is(target error) bool {
+	return target == ErrRejected
+}
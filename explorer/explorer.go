@@ -1,49 +1,166 @@
 package explorer
 
 import (
+	"context"
+	"log"
 	"republicofminer-client-go/common/websocket"
 	"republicofminer-client-go/explorer/api"
+	"time"
+
+	json "republicofminer-client-go/common/json"
 )
 
-var client *websocket.WebSocketClient
+// DefaultEndpoint is the explorer of the main network
+const DefaultEndpoint = "data.republicofminer.com:2030"
+
+// DefaultTimeout is the maximum duration we wait for the explorer to answer a request
+const DefaultTimeout = 30 * time.Second
+
+// Options configures the connection to an explorer, the zero value connects to DefaultEndpoint
+type Options struct {
+	// Endpoint is the host and port of the explorer
+	Endpoint string
+	// Secure connects with TLS (wss)
+	Secure bool
+	// Dialer opens the connection, its TLSClientConfig is used when Secure
+	Dialer *websocket.Dialer
+	// Logger receives the logs of the connection, the standard logger when nil
+	Logger *log.Logger
+	// Frames receives the raw messages of the connection, they are not logged when nil
+	Frames *log.Logger
+	// Timeout is the maximum duration we wait for an answer, DefaultTimeout when zero
+	Timeout time.Duration
+}
+
+// Explorer is a client of the blockchain explorer
+type Explorer struct {
+	client  *websocket.WebSocketClient
+	options Options
+}
+
+// New instanciates an explorer client, the requests sent before Connect wait for the connection
+func New(options Options) *Explorer {
+	if options.Endpoint == "" {
+		options.Endpoint = DefaultEndpoint
+	}
+	if options.Timeout == 0 {
+		options.Timeout = DefaultTimeout
+	}
+
+	client := websocket.Client(api.CreateResponse)
+	client.NotificationFactory(api.CreateNotification)
+	client.Dialer = options.Dialer
+	client.Logger = options.Logger
+	client.Frames = options.Frames
+
+	return &Explorer{client: client, options: options}
+}
+
+// Connect to the explorer and blocks the thread until Close is called
+func (explorer *Explorer) Connect() {
+	scheme := "ws"
+	if explorer.options.Secure {
+		scheme = "wss"
+	}
+	explorer.client.Connect(scheme + "://" + explorer.options.Endpoint)
+}
+
+// Close the connection to the explorer
+func (explorer *Explorer) Close() {
+	explorer.client.Close()
+}
 
-func Connect() {
-	client = websocket.Client(api.CreateResponse)
-	client.Connect("data.republicofminer.com:2030")
+func (explorer *Explorer) do(request json.Request, t string) (json.Response, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), explorer.options.Timeout)
+	defer cancel()
+	return explorer.client.Do(ctx, request, t)
 }
 
-func GetTransaction(hash string) *api.Transaction {
+func (explorer *Explorer) GetTransaction(hash string) (*api.Transaction, error) {
 	request := api.GetTransactionRequest{Hash: hash}
-	response := <-client.Request(client.RequestMessage(&request, "GetTransactionRequest"))
-	data := response.Data.(*api.GetTransactionResponse)
-	return &data.Transaction
+	response, err := explorer.do(&request, "GetTransactionRequest")
+	if err != nil {
+		return nil, err
+	}
+	data, ok := response.(*api.GetTransactionResponse)
+	if !ok {
+		return nil, ErrUnexpectedResponse
+	}
+	if data.Transaction.Hash == "" {
+		return nil, ErrNotFound
+	}
+	return &data.Transaction, nil
 }
 
-func GetLedgerByHash(hash string) *api.Ledger {
-	return GetLedger(&api.GetLedgerRequest{Hash: hash})
+func (explorer *Explorer) GetLedgerByHash(hash string) (*api.Ledger, error) {
+	return explorer.GetLedger(&api.GetLedgerRequest{Hash: hash})
 }
 
-func GetLedgerByHeight(height int64) *api.Ledger {
-	return GetLedger(&api.GetLedgerRequest{Height: &height})
+func (explorer *Explorer) GetLedgerByHeight(height int64) (*api.Ledger, error) {
+	return explorer.GetLedger(&api.GetLedgerRequest{Height: &height})
 }
 
-func GetLedger(request *api.GetLedgerRequest) *api.Ledger {
-	response := <-client.Request(client.RequestMessage(request, "GetLedgerRequest"))
-	data := response.Data.(*api.GetLedgerResponse)
-	return &data.Ledger
+func (explorer *Explorer) GetLedger(request *api.GetLedgerRequest) (*api.Ledger, error) {
+	response, err := explorer.do(request, "GetLedgerRequest")
+	if err != nil {
+		return nil, err
+	}
+	data, ok := response.(*api.GetLedgerResponse)
+	if !ok {
+		return nil, ErrUnexpectedResponse
+	}
+	if data.Ledger.Hash == "" {
+		return nil, ErrNotFound
+	}
+	return &data.Ledger, nil
 }
 
-func SendTransaction(transaction *api.Transaction, signatures []*api.Signature) string {
+func (explorer *Explorer) SendTransaction(transaction *api.Transaction, signatures []*api.Signature) (string, error) {
 	request := api.SendTransactionRequest{Transaction: transaction, Signatures: signatures}
-	response := <-client.Request(client.RequestMessage(&request, "SendTransactionRequest"))
-	data := response.Data.(*api.SendTransactionResponse)
-	return data.Hash
+	response, err := explorer.do(&request, "SendTransactionRequest")
+	if result, ok := err.(*ServerError); ok {
+		return "", &RejectedError{result}
+	}
+	if err != nil {
+		return "", err
+	}
+	data, ok := response.(*api.SendTransactionResponse)
+	if !ok {
+		return "", ErrUnexpectedResponse
+	}
+	return data.Hash, nil
 }
 
 // TODO make an account struct
-func GetAccount(encoded string) *api.GetAccountResponse {
+func (explorer *Explorer) GetAccount(encoded string) (*api.GetAccountResponse, error) {
 	request := api.GetAccountRequest{Address: encoded}
-	response := <-client.Request(client.RequestMessage(&request, "GetAccountRequest"))
-	data := response.Data.(*api.GetAccountResponse)
-	return data
+	response, err := explorer.do(&request, "GetAccountRequest")
+	if err != nil {
+		return nil, err
+	}
+	data, ok := response.(*api.GetAccountResponse)
+	if !ok {
+		return nil, ErrUnexpectedResponse
+	}
+	if data.Address == "" {
+		return nil, ErrNotFound
+	}
+	return data, nil
+}
+
+// OnLedger calls the handler every time the explorer pushes a new ledger, it returns the function to unsubscribe
+func (explorer *Explorer) OnLedger(handler func(ledger *api.Ledger)) func() {
+	return explorer.client.Subscribe("LedgerNotification", func(notification json.Notification) {
+		handler(&notification.(*api.LedgerNotification).Ledger)
+	})
+}
+
+// OnAccount calls the handler every time the explorer pushes a change of the account, it returns the function to unsubscribe
+func (explorer *Explorer) OnAccount(address string, handler func(account *api.AccountNotification)) func() {
+	return explorer.client.Subscribe("AccountNotification", func(notification json.Notification) {
+		account := notification.(*api.AccountNotification)
+		if account.Address == address {
+			handler(account)
+		}
+	})
 }
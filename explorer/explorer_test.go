@@ -0,0 +1,76 @@
+package explorer
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"republicofminer-client-go/common/websocket/websockettest"
+	"testing"
+	"time"
+)
+
+func connect(server *httptest.Server) *Explorer {
+	explorer := New(Options{Endpoint: websockettest.Address(server), Timeout: time.Second})
+	go explorer.Connect()
+	return explorer
+}
+
+func TestGetTransaction(t *testing.T) {
+	server := websockettest.Server(t, map[string]websockettest.Responder{
+		"GetTransactionRequest": func(data json.RawMessage) []websockettest.Message {
+			var request struct{ Hash string }
+			json.Unmarshal(data, &request)
+			if request.Hash != "known" {
+				return websockettest.Response("GetTransactionResponse", map[string]interface{}{}, 0)
+			}
+			return websockettest.Response("GetTransactionResponse", map[string]interface{}{"Transaction": map[string]interface{}{"Hash": "known"}}, 0)
+		},
+	})
+	defer server.Close()
+
+	explorer := connect(server)
+	defer explorer.Close()
+
+	transaction, err := explorer.GetTransaction("known")
+	if err != nil || transaction.Hash != "known" {
+		t.Fatal("expected the known transaction, actual", transaction, err)
+	}
+
+	_, err = explorer.GetTransaction("unknown")
+	if err != ErrNotFound {
+		t.Fatal("expected :", ErrNotFound, "actual", err)
+	}
+}
+
+func TestSendTransactionRejected(t *testing.T) {
+	server := websockettest.Server(t, map[string]websockettest.Responder{
+		"SendTransactionRequest": websockettest.Respond("SendTransactionResponse", map[string]interface{}{}, 4),
+	})
+	defer server.Close()
+
+	explorer := connect(server)
+	defer explorer.Close()
+
+	_, err := explorer.SendTransaction(nil, nil)
+	if !errors.Is(err, ErrRejected) {
+		t.Fatal("expected :", ErrRejected, "actual", err)
+	}
+
+	var result *ServerError
+	if !errors.As(err, &result) || result.ResultCode != 4 {
+		t.Fatal("expected the result code 4, actual", err)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	server := websockettest.Server(t, nil)
+	defer server.Close()
+
+	explorer := connect(server)
+	defer explorer.Close()
+
+	_, err := explorer.GetLedgerByHeight(10)
+	if err != ErrTimeout {
+		t.Fatal("expected :", ErrTimeout, "actual", err)
+	}
+}
@@ -1,8 +1,272 @@
 package main
 
-import "republicofminer-client-go/web"
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"republicofminer-client-go/explorer"
+	"republicofminer-client-go/republicofminer"
+	"republicofminer-client-go/vault"
+	"republicofminer-client-go/wallet"
+	"time"
+)
+
+// the exit codes of the commands, verify has its own
+const (
+	exitOK       = 0
+	exitFailure  = 1
+	exitUsage    = 2
+	exitNotFound = 3
+	// exitInterrupted is the code of the shells for a command ended by SIGINT
+	exitInterrupted = 130
+)
+
+const usage = `usage: republicofminer [global options] command [options] [arguments]
+
+commands:
+  serve     serve the explorer data over http, -mine also mines
+  mine      mine resources with an account of the wallet, -serve also serves
+  wallet    manage the accounts and the mnemonic of the wallet
+  vault     list, export and import the items of the vault, change its password
+  tx        print a transaction of the explorer
+  block     print a block of the explorer by height or hash
+  account   print the balance of an address
+  address   print the addresses of the accounts of the wallet
+  verify    verify a transaction and its signatures offline
+
+global options:
+`
+
+// options are the global options shared by the commands
+type options struct {
+	explorer       string
+	explorerTLS    bool
+	game           string
+	gameTLS        bool
+	timeout        time.Duration
+	dataDir        string
+	logLevel       string
+	passwordFile   string
+	nonInteractive bool
+	backend        string
+	// secret is the password of the vault once read
+	secret *string
+	// interrupted is done after the first interrupt or once the command returned, the clients are closed then
+	interrupted context.Context
+	signals     chan os.Signal
+}
+
+// command runs with the global options and the arguments following its name, it returns the exit code
+type command func(options *options, args []string) int
+
+var commands = map[string]command{
+	"serve":   serveCommand,
+	"mine":    mineCommand,
+	"wallet":  walletCommand,
+	"vault":   vaultCommand,
+	"tx":      txCommand,
+	"block":   blockCommand,
+	"account": accountCommand,
+	"address": addressCommand,
+	"verify":  func(options *options, args []string) int { return verifyCommand(args) },
+}
 
 func main() {
-	web.Run()
-	//miner.Run()
+	os.Exit(run(os.Args[1:]))
+}
+
+// run parses the global options and runs the command
+func run(args []string) int {
+	options := &options{}
+	flags := flag.NewFlagSet("republicofminer", flag.ContinueOnError)
+	flags.StringVar(&options.explorer, "explorer", explorer.DefaultEndpoint, "host and port of the explorer")
+	flags.BoolVar(&options.explorerTLS, "explorer-tls", false, "connect to the explorer with TLS")
+	flags.StringVar(&options.game, "game", republicofminer.DefaultEndpoint, "host and port of the game server")
+	flags.BoolVar(&options.gameTLS, "game-tls", false, "connect to the game server with TLS")
+	flags.DurationVar(&options.timeout, "timeout", explorer.DefaultTimeout, "maximum duration we wait for an answer of the explorer or the game server")
+	flags.StringVar(&options.dataDir, "data-dir", ".", "directory of the vault, the claim journal and the mining statistics")
+	flags.StringVar(&options.logLevel, "log-level", "info", "debug, info or error")
+	flags.StringVar(&options.passwordFile, "password-file", "", "file containing the password of the vault, or set "+wallet.PasswordFileEnv+" or "+wallet.PasswordEnv)
+	flags.BoolVar(&options.nonInteractive, "non-interactive", false, "never prompt for a password")
+	flags.StringVar(&options.backend, "vault-backend", vault.DefaultBackend, fmt.Sprintf("storage of the vault %v", vault.Backends()))
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return exitUsage
+	}
+	execute, ok := commands[flags.Arg(0)]
+	if !ok {
+		fmt.Fprintln(os.Stderr, "Unknown command :", flags.Arg(0))
+		flags.Usage()
+		return exitUsage
+	}
+	if err := options.setLogLevel(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+
+	options.signals = make(chan os.Signal, 1)
+	signal.Notify(options.signals, os.Interrupt)
+	defer signal.Stop(options.signals)
+	interrupted, interrupt := context.WithCancel(context.Background())
+	defer interrupt()
+	go func() {
+		select {
+		case <-options.signals:
+			interrupt()
+		case <-interrupted.Done():
+		}
+	}()
+	options.interrupted = interrupted
+	return execute(options, flags.Args()[1:])
+}
+
+// setLogLevel configures the standard logger used by every package
+func (options *options) setLogLevel() error {
+	switch options.logLevel {
+	case "debug":
+		log.SetFlags(log.LstdFlags | log.Lmicroseconds | log.Lshortfile)
+	case "info":
+	case "error":
+		// the errors are printed by the commands, the packages only log their progress
+		log.SetOutput(io.Discard)
+	default:
+		return fmt.Errorf("unknown log level %s, use debug, info or error", options.logLevel)
+	}
+	return nil
+}
+
+// frames logs the raw messages of the servers in debug only, the mining claims carry their secret
+func (options *options) frames() *log.Logger {
+	if options.logLevel != "debug" {
+		return nil
+	}
+	return log.Default()
+}
+
+// password reads the password of the vault once
+func (options *options) password() (string, error) {
+	if options.secret == nil {
+		password, err := options.prompt(wallet.Password{File: options.passwordFile, NonInteractive: options.nonInteractive})
+		if err != nil {
+			return "", err
+		}
+		options.secret = &password
+	}
+	return *options.secret, nil
+}
+
+// prompt reads the password, the prompt keeps the default interrupt that ends the process
+func (options *options) prompt(password wallet.Password) (string, error) {
+	signal.Stop(options.signals)
+	defer signal.Notify(options.signals, os.Interrupt)
+	return password.Get()
+}
+
+// vault unlocks the vault of the data directory
+func (options *options) vault() (*vault.Vault, error) {
+	if err := os.MkdirAll(options.dataDir, 0700); err != nil {
+		return nil, err
+	}
+	password, err := options.password()
+	if err != nil {
+		return nil, err
+	}
+	return vault.UnlockWith(options.backend, filepath.Join(options.dataDir, wallet.VaultName), password)
+}
+
+// accounts unlocks the vault and loads the accounts of the wallet
+func (options *options) accounts() (*vault.Vault, *wallet.Accounts, error) {
+	v, err := options.vault()
+	if err != nil {
+		return nil, nil, err
+	}
+	accounts, err := wallet.OpenAccounts(v)
+	if err != nil {
+		v.Lock()
+		return nil, nil, err
+	}
+	return v, accounts, nil
+}
+
+// connectExplorer connects to the explorer in the background, the requests wait for the connection
+func (options *options) connectExplorer() *explorer.Explorer {
+	client := explorer.New(explorer.Options{Endpoint: options.explorer, Secure: options.explorerTLS, Timeout: options.timeout, Frames: options.frames()})
+	go client.Connect()
+	go options.closeOnInterrupt(client.Close)
+	return client
+}
+
+// connectGame connects to the game server in the background
+func (options *options) connectGame() *republicofminer.GameServer {
+	client := republicofminer.New(republicofminer.Options{Endpoint: options.game, Secure: options.gameTLS, Timeout: options.timeout, Frames: options.frames()})
+	go client.Connect()
+	go options.closeOnInterrupt(client.Close)
+	return client
+}
+
+// closeOnInterrupt closes the client after an interrupt or once the command returned
+func (options *options) closeOnInterrupt(close func()) {
+	<-options.interrupted.Done()
+	close()
+}
+
+// newFlags creates the options of a command, the usage tells its arguments
+func newFlags(name string, arguments string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: republicofminer [global options] %s [options] %s\n", name, arguments)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// parse parses the options of the command and checks the number of arguments
+// when the command should not run, it returns false and the exit code, exitOK for -h
+func parse(flags *flag.FlagSet, args []string, min int, max int) (int, bool) {
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK, false
+		}
+		return exitUsage, false
+	}
+	if flags.NArg() < min || flags.NArg() > max {
+		flags.Usage()
+		return exitUsage, false
+	}
+	return exitOK, true
+}
+
+// isHelp tells if the argument asks for the usage like the -h of the flag package
+func isHelp(arg string) bool {
+	switch arg {
+	case "-h", "-help", "--h", "--help":
+		return true
+	}
+	return false
+}
+
+// failed prints the error and returns the exit code matching it
+func failed(message string, err error) int {
+	fmt.Fprintln(os.Stderr, message, ":", err)
+	if errors.Is(err, explorer.ErrNotFound) {
+		return exitNotFound
+	}
+	return exitFailure
 }
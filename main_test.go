@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"republicofminer-client-go/common/websocket/websockettest"
+	"republicofminer-client-go/miner"
+	"republicofminer-client-go/wallet"
+	"testing"
+)
+
+func TestExitCodes(t *testing.T) {
+	t.Setenv(wallet.PasswordEnv, "thisisapassword")
+	t.Setenv("ROM_ARCHIVE_PASSWORD", "thisisthearchivepassword")
+	archive := filepath.Join(t.TempDir(), "backup.archive")
+	// the claim journal cannot be read when it is a directory
+	unreadable := t.TempDir()
+	if err := os.Mkdir(filepath.Join(unreadable, miner.JournalFile), 0700); err != nil {
+		t.Fatal(err)
+	}
+	explorer := websockettest.Server(t, map[string]websockettest.Responder{
+		"GetTransactionRequest": websockettest.Respond("GetTransactionResponse", map[string]interface{}{}, 0),
+	})
+	global := []string{"-vault-backend", "memory", "-non-interactive", "-log-level", "error", "-data-dir", t.TempDir(), "-explorer", websockettest.Address(explorer)}
+
+	// the memory backend opens an empty vault for every command
+	tests := []struct {
+		args     []string
+		expected int
+	}{
+		{[]string{"-h"}, exitOK},
+		{[]string{"-unknown"}, exitUsage},
+		{[]string{}, exitUsage},
+		{[]string{"unknown"}, exitUsage},
+		{[]string{"tx", "-h"}, exitOK},
+		{[]string{"tx"}, exitUsage},
+		{[]string{"tx", "-unknown", "hash"}, exitUsage},
+		{[]string{"tx", "unknownhash"}, exitNotFound},
+		{[]string{"verify", "-h"}, exitOK},
+		{[]string{"verify", "a", "b"}, exitUsage},
+		{[]string{"wallet", "-h"}, exitOK},
+		{[]string{"wallet"}, exitUsage},
+		{[]string{"wallet", "create"}, exitUsage},
+		{[]string{"wallet", "derive", "-h"}, exitOK},
+		{[]string{"vault", "-h"}, exitOK},
+		{[]string{"vault", "import", "-h"}, exitOK},
+		{[]string{"vault", "import"}, exitUsage},
+		{[]string{"mine", "-h"}, exitOK},
+		{[]string{"mine", "-weights", "WOD"}, exitUsage},
+		{[]string{"mine", "-strategy", "unknown"}, exitUsage},
+		{[]string{"-data-dir", unreadable, "mine"}, exitFailure},
+		{[]string{"address", "nosuch"}, exitFailure},
+		{[]string{"wallet", "create", "treasury"}, exitOK},
+		{[]string{"wallet", "list"}, exitOK},
+		{[]string{"vault", "list"}, exitOK},
+		{[]string{"vault", "export", "-o", archive}, exitOK},
+		{[]string{"vault", "export", "-o", archive}, exitFailure},
+		{[]string{"vault", "import", archive}, exitOK},
+	}
+	for _, test := range tests {
+		args := append(append([]string(nil), global...), test.args...)
+		if code := run(args); code != test.expected {
+			t.Fatal(test.args, "expected :", test.expected, "actual", code)
+		}
+	}
+}
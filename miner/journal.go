@@ -0,0 +1,157 @@
+package miner
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	game "republicofminer-client-go/republicofminer/api"
+	"sort"
+	"sync"
+	"time"
+)
+
+// State is the step of the claim of a mining task
+type State string
+
+const (
+	// Received means we are searching the secret of the task
+	Received State = "received"
+	// Solved means we found the secret but the claim is not sent, or sending it failed
+	Solved State = "solved"
+	// Submitted means the claim transaction was sent and waits for a ledger
+	Submitted State = "submitted"
+	// Included means the claim transaction is in a ledger
+	Included State = "included"
+	// Confirmed means enough ledgers were closed after the one including the claim
+	Confirmed State = "confirmed"
+	// Abandoned means the search failed or the claim was rejected too many times, a found secret stays in the journal
+	Abandoned State = "abandoned"
+)
+
+// Claim follows a mining task from its reception to the confirmation of the reward
+type Claim struct {
+	Resource string
+	Task     *game.MiningTask
+	State    State
+	// Secret is the secret of the task in base 64
+	Secret string `json:",omitempty"`
+	// Transaction is the hash of the last claim transaction sent
+	Transaction string `json:",omitempty"`
+	// Expire is the unix time after which the claim transaction cannot be included anymore
+	Expire int64 `json:",omitempty"`
+	// Height is the height of the ledger including the claim transaction
+	Height   int64  `json:",omitempty"`
+	Attempts int    `json:",omitempty"`
+	Error    string `json:",omitempty"`
+	Updated  time.Time
+}
+
+// JournalFile is the name of the journal in the data directory
+const JournalFile = "claims.json"
+
+// Journal keeps the claims that are not finished, on disk when it has a data directory
+// a restarted miner picks up the claims of its journal
+type Journal struct {
+	mutex  sync.Mutex
+	path   string
+	claims map[string]*Claim
+}
+
+// OpenJournal loads the journal of the data directory, the journal is only kept in memory when the directory is empty
+func OpenJournal(dir string) (*Journal, error) {
+	journal := &Journal{claims: make(map[string]*Claim)}
+	if dir == "" {
+		return journal, nil
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	journal.path = filepath.Join(dir, JournalFile)
+
+	if err := load(journal.path, &journal.claims); err != nil {
+		return nil, err
+	}
+	return journal, nil
+}
+
+// Update saves a copy of the claim, the confirmed claims and the abandoned claims without secret leave the journal
+// the secret of an abandoned claim is kept to claim the reward by hand
+func (journal *Journal) Update(claim *Claim) error {
+	journal.mutex.Lock()
+	defer journal.mutex.Unlock()
+
+	claim.Updated = time.Now()
+	if claim.State == Confirmed || (claim.State == Abandoned && claim.Secret == "") {
+		delete(journal.claims, claim.Task.Address)
+	} else {
+		copied := *claim
+		journal.claims[claim.Task.Address] = &copied
+	}
+	return journal.save()
+}
+
+// Claims returns a copy of the claims in the given states, the oldest first
+func (journal *Journal) Claims(states ...State) []*Claim {
+	journal.mutex.Lock()
+	defer journal.mutex.Unlock()
+
+	claims := []*Claim{}
+	for _, claim := range journal.claims {
+		for _, state := range states {
+			if claim.State == state {
+				copied := *claim
+				claims = append(claims, &copied)
+				break
+			}
+		}
+	}
+	sort.Slice(claims, func(i, j int) bool { return claims[i].Updated.Before(claims[j].Updated) })
+	return claims
+}
+
+// Claim returns a copy of the claim of the task, false when the claim is finished or unknown
+func (journal *Journal) Claim(task string) (*Claim, bool) {
+	journal.mutex.Lock()
+	defer journal.mutex.Unlock()
+
+	claim, ok := journal.claims[task]
+	if !ok {
+		return nil, false
+	}
+	copied := *claim
+	return &copied, true
+}
+
+// save writes the journal, the caller must hold the mutex
+func (journal *Journal) save() error {
+	if journal.path == "" {
+		return nil
+	}
+	return store(journal.path, journal.claims)
+}
+
+// load reads the json file into value, a missing file leaves the value untouched
+func load(path string, value interface{}) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, value)
+}
+
+// store writes the value as json in a temporary file renamed over the file, so a crash never leaves a partial file
+func store(path string, value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	temporary := path + ".tmp"
+	if err := os.WriteFile(temporary, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(temporary, path)
+}
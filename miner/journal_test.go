@@ -0,0 +1,76 @@
+package miner
+
+import (
+	"republicofminer-client-go/explorer/api"
+	game "republicofminer-client-go/republicofminer/api"
+	"testing"
+)
+
+func TestJournal(t *testing.T) {
+	dir := t.TempDir()
+	journal, err := OpenJournal(dir)
+	if err != nil {
+		t.Fatal("Error opening the journal :", err)
+	}
+
+	journal.Update(&Claim{Resource: "WOD", Task: &game.MiningTask{Address: "first"}, State: Received})
+	journal.Update(&Claim{Resource: "IRO", Task: &game.MiningTask{Address: "second"}, State: Submitted, Transaction: "hash"})
+	journal.Update(&Claim{Resource: "STN", Task: &game.MiningTask{Address: "third"}, State: Submitted})
+	journal.Update(&Claim{Resource: "STN", Task: &game.MiningTask{Address: "third"}, State: Confirmed})
+
+	// a restarted miner finds the unfinished claims
+	reopened, err := OpenJournal(dir)
+	if err != nil {
+		t.Fatal("Error reopening the journal :", err)
+	}
+	if claims := reopened.Claims(Received, Submitted, Confirmed); len(claims) != 2 {
+		t.Fatal("expected : 2 claims actual", len(claims))
+	}
+	submitted := reopened.Claims(Submitted)
+	if len(submitted) != 1 || submitted[0].Task.Address != "second" || submitted[0].Transaction != "hash" {
+		t.Fatal("expected the submitted claim, actual", submitted)
+	}
+
+	// the returned claims are copies
+	submitted[0].State = Included
+	if len(reopened.Claims(Included)) != 0 {
+		t.Fatal("the journal should only change on Update")
+	}
+}
+
+func TestLedgerConfirmsClaims(t *testing.T) {
+	journal, _ := OpenJournal("")
+	stats, _ := OpenStats("")
+	miner := &Miner{journal: journal, stats: stats, confirmations: 2}
+	journal.Update(&Claim{Resource: "WOD", Task: &game.MiningTask{Address: "task", Amount: 1.5}, State: Submitted, Transaction: "claim"})
+
+	expected := []State{Submitted, Included, Included, Confirmed}
+	for height, state := range expected {
+		ledger := &api.Ledger{Height: int64(10 + height)}
+		if height == 1 {
+			ledger.Transactions = []*api.TransactionHeader{&api.TransactionHeader{Hash: "other"}, &api.TransactionHeader{Hash: "claim"}}
+		}
+		miner.ledger(ledger)
+
+		claims := journal.Claims(state)
+		if state == Confirmed {
+			// the confirmed claims leave the journal
+			if len(journal.Claims(Submitted, Included)) != 0 {
+				t.Fatal("the confirmed claim should leave the journal")
+			}
+			if confirmed := stats.Resources()["WOD"].Confirmed; confirmed != 1.5 {
+				t.Fatal("expected : 1.5 actual", confirmed)
+			}
+			continue
+		}
+		if len(claims) != 1 {
+			t.Fatal("ledger", 10+height, "expected :", state)
+		}
+		if confirmed := stats.Resources()["WOD"].Confirmed; confirmed != 0 {
+			t.Fatal("the claim is not confirmed yet, actual", confirmed)
+		}
+		if state == Included && claims[0].Height != 11 {
+			t.Fatal("expected : 11 actual", claims[0].Height)
+		}
+	}
+}
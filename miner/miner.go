@@ -2,41 +2,231 @@ package miner
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
 	"math/rand"
 	"republicofminer-client-go/explorer"
 	"republicofminer-client-go/explorer/api"
 	"republicofminer-client-go/protocol"
 	"republicofminer-client-go/protocol/converter/protocoltoapi"
 	"republicofminer-client-go/republicofminer"
+	game "republicofminer-client-go/republicofminer/api"
 	"republicofminer-client-go/wallet"
+	"sync"
 	"time"
 )
 
-func Run() {
-	go explorer.Connect()
-	go republicofminer.Connect()
-	wallet.Load()
+// RetryDelay is the time we wait before asking a new task when the game server fails
+var RetryDelay = 5 * time.Second
+
+// TaskSecretHashType is the algorithm of the secret hashes of the tasks, the game server does not send one
+const TaskSecretHashType = protocol.SHA3
+
+// Config tunes the miner
+type Config struct {
+	// Workers is the number of goroutines searching the secrets, one per core when zero
+	Workers int
+	// Strategy is the name of the strategy deciding which resource to mine, random when empty
+	Strategy string
+	// Weights are the weights of the resources for the random strategy, the same for all when empty
+	Weights map[string]float64
+	// Goals are the balances the inventory strategy tries to reach for each resource
+	Goals map[string]float64
+	// Claim is the way the rewards are claimed, through the explorer when empty
+	Claim string
+	// DataDir is the directory of the claim journal and the statistics, they are lost on restart when empty
+	DataDir string
+	// Confirmations is the number of ledgers after the one including a claim before it is confirmed, DefaultConfirmations when zero
+	Confirmations int64
+}
+
+// the ways to claim a reward in the Config
+const (
+	// ClaimThroughExplorer signs the claim transaction with the wallet and sends it to the explorer
+	ClaimThroughExplorer = "explorer"
+	// ClaimThroughServer sends the secret to the game server that claims for us, the explorer is used when it fails
+	ClaimThroughServer = "server"
+)
+
+// Miner requests mining tasks to the game server and claims the rewards through the explorer or the game server
+type Miner struct {
+	explorer *explorer.Explorer
+	game     *republicofminer.GameServer
+	wallet   *wallet.Wallet
+	pool     *Pool
+	strategy Strategy
+	server   bool
+	journal  *Journal
+	stats    *Stats
+
+	// mutex serializes the changes of the claims between Run and the tracker, it is never held during a request
+	mutex         sync.Mutex
+	height        int64
+	confirmations int64
+	// submitting lists the tasks whose claim is being sent
+	submitting map[string]bool
+}
+
+// ErrInvalidConfig is returned by New when the config is wrong, the other errors come from the data directory
+var ErrInvalidConfig = errors.New("miner: invalid config")
+
+func New(explorer *explorer.Explorer, game *republicofminer.GameServer, wallet *wallet.Wallet, config Config) (*Miner, error) {
+	strategy, err := NewStrategy(config, explorer, wallet.Address.Encoded)
+	if err != nil {
+		return nil, err
+	}
+	if config.Claim != "" && config.Claim != ClaimThroughExplorer && config.Claim != ClaimThroughServer {
+		return nil, fmt.Errorf("%w : unknown claim mode %q", ErrInvalidConfig, config.Claim)
+	}
+	journal, err := OpenJournal(config.DataDir)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := OpenStats(config.DataDir)
+	if err != nil {
+		return nil, err
+	}
+	confirmations := config.Confirmations
+	if confirmations == 0 {
+		confirmations = DefaultConfirmations
+	}
+
+	return &Miner{
+		explorer:      explorer,
+		game:          game,
+		wallet:        wallet,
+		pool:          NewPool(config.Workers),
+		strategy:      strategy,
+		server:        config.Claim == ClaimThroughServer,
+		journal:       journal,
+		stats:         stats,
+		confirmations: confirmations,
+		submitting:    map[string]bool{},
+	}, nil
+}
+
+// Run mines forever, the claims of the journal are picked up before asking new tasks
+func (miner *Miner) Run() {
+	go miner.track()
+	go miner.report()
 
 	for {
-		task := republicofminer.GetMiningTask(wallet.Address.Encoded, resource())
-		hash, _ := base64.StdEncoding.DecodeString(task.SecretHash)
-		mask, _ := base64.StdEncoding.DecodeString(task.Mask)
-		secret := mine(hash, mask)
-		address := protocol.DecodeAddress(task.Address)
-		amount := protocol.Amount(task.Amount)
-		currency := protocol.CurrencyFromSymbol(task.Currency)
-		transaction := claim(*address, *wallet.Address, amount, currency, secret)
-		pub, signature := wallet.Sign(transaction.Hash().ToBytes())
-		explorer.SendTransaction(protocoltoapi.ToTransaction(transaction), []*api.Signature{&api.Signature{
-			PublicKey:     pub.ToBase64(),
-			SignatureByte: signature.ToBase64(),
-		}})
+		claim, err := miner.next()
+		if err != nil {
+			log.Println("Get mining task failed :", err)
+			time.Sleep(RetryDelay)
+			continue
+		}
+		hash, _ := base64.StdEncoding.DecodeString(claim.Task.SecretHash)
+		mask, _ := base64.StdEncoding.DecodeString(claim.Task.Mask)
+		result, err := miner.pool.Mine(context.Background(), TaskSecretHashType, hash, mask)
+		if err != nil {
+			log.Println("Mine secret failed :", err)
+			if result != nil {
+				miner.record(miner.stats.Failed(claim.Resource, result))
+			}
+			miner.mutex.Lock()
+			claim.State = Abandoned
+			miner.update(claim)
+			miner.mutex.Unlock()
+			continue
+		}
+		log.Printf("Secret found in %v with %d workers at %.0f H/s", result.Duration, miner.pool.Workers(), result.Hashrate())
+		miner.strategy.Observe(claim.Resource, claim.Task, result)
+		miner.record(miner.stats.Solved(claim.Resource, result))
+
+		miner.mutex.Lock()
+		claim.State = Solved
+		claim.Secret = result.Secret.Secret.ToBase64()
+		miner.update(claim)
+		miner.mutex.Unlock()
+		miner.submit(claim)
+	}
+}
+
+// next returns the oldest task of the journal we did not solve or asks a new one to the game server
+func (miner *Miner) next() (*Claim, error) {
+	if received := miner.journal.Claims(Received); len(received) > 0 {
+		log.Println("Resume mining task :", received[0].Task.Address)
+		return received[0], nil
+	}
+
+	resource, err := miner.strategy.Next()
+	if err != nil {
+		return nil, err
+	}
+	task, err := miner.game.GetMiningTask(miner.wallet.Address.Encoded, resource)
+	if err != nil {
+		return nil, err
+	}
+
+	claim := &Claim{Resource: resource, Task: task, State: Received}
+	miner.update(claim)
+	miner.record(miner.stats.Received(resource))
+	return claim, nil
+}
+
+// Stats returns the mining statistics
+func (miner *Miner) Stats() *Stats {
+	return miner.stats
+}
+
+// report logs the summary of the statistics every SummaryInterval
+func (miner *Miner) report() {
+	ticker := time.NewTicker(SummaryInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		log.Printf("Mining statistics :\n%s", miner.stats.Summary())
+	}
+}
+
+// record logs the statistics that cannot be saved, we keep mining
+func (miner *Miner) record(err error) {
+	if err != nil {
+		log.Println("Save mining statistics failed :", err)
+	}
+}
+
+// claim sends the claim of the task reward and returns the hash of the claim transaction
+func (miner *Miner) claim(task *game.MiningTask, secret *protocol.SecretRevelation) (string, error) {
+	if miner.server {
+		txhash, err := miner.game.ClaimMining(task.Address, secret.Secret.ToBase64(), miner.wallet.Address.Encoded)
+		if err == nil {
+			return txhash, nil
+		}
+		log.Println("Claim through the game server failed, sending the claim to the explorer :", err)
+	}
+	return miner.send(task, secret)
+}
+
+// send signs the claim transaction and sends it to the explorer
+func (miner *Miner) send(task *game.MiningTask, secret *protocol.SecretRevelation) (string, error) {
+	address := protocol.DecodeAddress(task.Address)
+	// the amount of the task is already in the smallest unit, unlike the amounts of the explorer
+	amount := protocol.Amount(task.Amount)
+	currency := protocol.CurrencyFromSymbol(task.Currency)
+	transaction := claim(*address, *miner.wallet.Address, amount, currency, secret)
+	txhash, err := transaction.Hash()
+	if err != nil {
+		return "", err
+	}
+	pub, signature, err := miner.wallet.Sign(txhash.ToBytes())
+	if err != nil {
+		return "", err
 	}
+	// the conversion cannot fail once the transaction is hashed
+	tx, _ := protocoltoapi.ToTransaction(transaction)
+	return miner.explorer.SendTransaction(tx, []*api.Signature{&api.Signature{
+		PublicKey:     pub.ToBase64(),
+		SignatureByte: signature.ToBase64(),
+	}})
 }
 
-// we try to find the secret matching with the given secret hash
-func mine(secret []byte, mask []byte) *protocol.SecretRevelation {
+// we try to find the secret matching with the given secret hash on a single goroutine, see Pool for the parallel search
+func mine(typ protocol.SecretHashType, secret []byte, mask []byte) *protocol.SecretRevelation {
 	complexity := protocol.SECRET_SIZE - len(mask)
 	// the mask is the first part of the secret
 	buffer := append(mask, make([]byte, complexity)...)
@@ -48,7 +238,10 @@ func mine(secret []byte, mask []byte) *protocol.SecretRevelation {
 		// brute force the last byte
 		for b := byte(0); ; b++ {
 			buffer[last] = b
-			h := protocol.NewSecretRevelation(protocol.Secret(buffer))
+			h, err := protocol.RevealSecret(typ, protocol.Secret(buffer))
+			if err != nil {
+				return nil
+			}
 			// check if the hash matches with the secret
 			if bytes.Equal(secret, h.Hash) {
 				// fmt.Println("Secret Hash found !")
@@ -63,17 +256,10 @@ func mine(secret []byte, mask []byte) *protocol.SecretRevelation {
 
 func claim(sender protocol.Address, receiver protocol.Address, amount protocol.Amount, currency protocol.Currency, secret *protocol.SecretRevelation) *protocol.Transaction {
 	transaction := protocol.Transaction{
-		Expire:       time.Now().Add(time.Minute * 10).Unix(),
+		Expire:       time.Now().Add(ClaimExpiry).Unix(),
 		Declarations: []*protocol.TxDeclaration{&protocol.TxDeclaration{Type: protocol.TxSecret, Declaration: secret}},
 		Inputs:       []*protocol.TxInput{&protocol.TxInput{Address: sender, Amount: amount, Currency: currency}},
 		Outputs:      []*protocol.TxOutput{&protocol.TxOutput{Address: receiver, Amount: amount, Currency: currency}},
 	}
 	return &transaction
 }
-
-// TODO strategy to decide which resource to mine
-func resource() string {
-	// get one at random
-	candidates := []string{"WOD", "STN", "IRO"}
-	return candidates[rand.Intn(len(candidates))]
-}
@@ -0,0 +1,178 @@
+package miner
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"republicofminer-client-go/protocol"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// ErrExhausted is returned when no secret of the search space matches the hash
+var ErrExhausted = errors.New("miner: no secret matches the hash")
+
+// the number of unknown bytes enumerated by the workers, the other unknown bytes are drawn at random
+const counterSize = 8
+
+// the workers check the context every time they computed that many hashes
+const checkInterval = 4096
+
+// Pool brute forces the unknown bytes of the secret with several goroutines
+type Pool struct {
+	workers int
+}
+
+// NewPool creates a pool of the given number of workers, one per core when it is not positive
+func NewPool(workers int) *Pool {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	return &Pool{workers: workers}
+}
+
+// Workers is the number of goroutines used to search a secret
+func (pool *Pool) Workers() int {
+	return pool.workers
+}
+
+// Result is the secret found by the pool and the work it took
+type Result struct {
+	Secret   *protocol.SecretRevelation
+	Hashes   uint64
+	Duration time.Duration
+}
+
+// Hashrate is the combined number of hashes per second of the workers
+func (result *Result) Hashrate() float64 {
+	if result.Duration <= 0 {
+		return 0
+	}
+	return float64(result.Hashes) / result.Duration.Seconds()
+}
+
+// hasher computes the hash of the secret in sum, a worker reuses its hasher for every secret
+type hasher func(sum []byte, secret []byte) []byte
+
+func newHasher(typ protocol.SecretHashType) (hasher, error) {
+	switch typ {
+	case protocol.SHA3:
+		keccak := sha3.NewLegacyKeccak256()
+		return func(sum []byte, secret []byte) []byte {
+			keccak.Reset()
+			keccak.Write(secret)
+			return keccak.Sum(sum[:0])
+		}, nil
+	case protocol.SHA256:
+		return func(sum []byte, secret []byte) []byte {
+			hash := sha256.Sum256(secret)
+			return append(sum[:0], hash[:]...)
+		}, nil
+	case protocol.DoubleSHA256:
+		return func(sum []byte, secret []byte) []byte {
+			first := sha256.Sum256(secret)
+			hash := sha256.Sum256(first[:])
+			return append(sum[:0], hash[:]...)
+		}, nil
+	}
+	return nil, protocol.ErrUnknownSecretHashType
+}
+
+// Mine searches the secret starting with the mask whose hash with the algorithm is the hash
+// the unknown bytes are a counter, each worker tries every n-th value so no secret is tried twice
+// all the workers stop as soon as one of them finds the secret or the context is done
+func (pool *Pool) Mine(ctx context.Context, typ protocol.SecretHashType, hash []byte, mask []byte) (*Result, error) {
+	if _, err := newHasher(typ); err != nil {
+		return nil, err
+	}
+	if len(mask) > protocol.SECRET_SIZE {
+		return nil, fmt.Errorf("miner: the mask is longer than the secret (%d bytes)", len(mask))
+	}
+
+	prefix := make([]byte, protocol.SECRET_SIZE)
+	copy(prefix, mask)
+	counted := protocol.SECRET_SIZE - len(mask)
+	if counted > counterSize {
+		// the counter will not wrap before we find the secret, the remaining unknown bytes are shared by all the workers
+		rand.Read(prefix[len(mask) : protocol.SECRET_SIZE-counterSize])
+		counted = counterSize
+	}
+	// every value from 0 to last is tried once by one of the workers
+	last := uint64(math.MaxUint64) >> (64 - 8*uint(counted))
+
+	search, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	found := make(chan *protocol.SecretRevelation, pool.workers)
+	var hashes uint64
+	var group sync.WaitGroup
+	start := time.Now()
+
+	for worker := 0; worker < pool.workers; worker++ {
+		group.Add(1)
+		go func(first uint64) {
+			defer group.Done()
+			secret, done := brute(search, typ, hash, prefix, counted, first, uint64(pool.workers), last)
+			atomic.AddUint64(&hashes, done)
+			if secret != nil {
+				found <- secret
+				cancel()
+			}
+		}(uint64(worker))
+	}
+	group.Wait()
+
+	result := &Result{Hashes: hashes, Duration: time.Since(start)}
+	select {
+	case result.Secret = <-found:
+		return result, nil
+	default:
+	}
+
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+	return result, ErrExhausted
+}
+
+// brute tries the counter values first, first + stride, ... up to last and returns the number of hashes computed
+func brute(ctx context.Context, typ protocol.SecretHashType, hash []byte, prefix []byte, counted int, first uint64, stride uint64, last uint64) (*protocol.SecretRevelation, uint64) {
+	buffer := append([]byte(nil), prefix...)
+	counter := buffer[len(buffer)-counted:]
+	// the type is checked by Mine
+	compute, _ := newHasher(typ)
+	sum := make([]byte, 0, protocol.SECRET_HASH_SIZE)
+
+	done := uint64(0)
+	for value := first; value <= last; value += stride {
+		if done%checkInterval == 0 && ctx.Err() != nil {
+			return nil, done
+		}
+
+		for index := range counter {
+			counter[len(counter)-1-index] = byte(value >> (8 * uint(index)))
+		}
+
+		sum = compute(sum, buffer)
+		done++
+
+		if bytes.Equal(sum, hash) {
+			revelation, _ := protocol.RevealSecret(typ, protocol.Secret(buffer))
+			return revelation, done
+		}
+
+		// the next value would overflow
+		if last-value < stride {
+			break
+		}
+	}
+	return nil, done
+}
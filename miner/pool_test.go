@@ -0,0 +1,105 @@
+package miner
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"republicofminer-client-go/protocol"
+	"testing"
+	"time"
+)
+
+// task generates a secret whose last unknown bytes have to be found
+func task(r *rand.Rand, typ protocol.SecretHashType, unknown int) (secret []byte, hash []byte, mask []byte) {
+	secret = make([]byte, protocol.SECRET_SIZE)
+	r.Read(secret)
+	revelation, _ := protocol.RevealSecret(typ, protocol.Secret(secret))
+	return secret, revelation.Hash, secret[:protocol.SECRET_SIZE-unknown]
+}
+
+func TestPoolFindsSecret(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, workers := range []int{1, 3, 8} {
+		secret, hash, mask := task(r, protocol.SHA3, 2)
+		result, err := NewPool(workers).Mine(context.Background(), protocol.SHA3, hash, mask)
+		if err != nil {
+			t.Fatal("Error mining the secret :", err)
+		}
+		if !bytes.Equal(result.Secret.Secret, secret) || !bytes.Equal(result.Secret.Hash, hash) {
+			t.Fatal("expected :", secret, "actual", result.Secret.Secret)
+		}
+		if result.Hashes == 0 || result.Hashrate() <= 0 {
+			t.Fatal("the work should be reported, actual", result.Hashes, result.Hashrate())
+		}
+	}
+}
+
+func TestPoolSecretHashTypes(t *testing.T) {
+	r := rand.New(rand.NewSource(4))
+	for _, typ := range []protocol.SecretHashType{protocol.SHA3, protocol.SHA256, protocol.DoubleSHA256} {
+		secret, hash, mask := task(r, typ, 2)
+		result, err := NewPool(2).Mine(context.Background(), typ, hash, mask)
+		if err != nil || !bytes.Equal(result.Secret.Secret, secret) {
+			t.Fatal("type", typ, "expected :", secret, "actual", result, err)
+		}
+		if single := mine(typ, hash, append([]byte(nil), mask...)); !bytes.Equal(single.Secret, secret) {
+			t.Fatal("type", typ, "expected :", secret, "actual", single.Secret)
+		}
+	}
+
+	if _, err := NewPool(2).Mine(context.Background(), protocol.DoubleSHA256+1, nil, nil); err != protocol.ErrUnknownSecretHashType {
+		t.Fatal("expected :", protocol.ErrUnknownSecretHashType, "actual", err)
+	}
+}
+
+func TestPoolSearchesEverySecretOnce(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	_, _, mask := task(r, protocol.SHA3, 1)
+	for _, workers := range []int{1, 3, 7, 300} {
+		// no secret matches, the whole space is searched
+		result, err := NewPool(workers).Mine(context.Background(), protocol.SHA3, make([]byte, 32), mask)
+		if err != ErrExhausted {
+			t.Fatal("expected :", ErrExhausted, "actual", err)
+		}
+		if result.Hashes != 256 {
+			t.Fatal("expected : 256 hashes with", workers, "workers, actual", result.Hashes)
+		}
+	}
+}
+
+func TestPoolCancel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := NewPool(4).Mine(ctx, protocol.SHA3, make([]byte, 32), nil)
+	if err != context.DeadlineExceeded {
+		t.Fatal("expected :", context.DeadlineExceeded, "actual", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatal("the workers should stop with the context, stopped after", elapsed)
+	}
+}
+
+func BenchmarkMine(b *testing.B) {
+	r := rand.New(rand.NewSource(3))
+	for i := 0; i < b.N; i++ {
+		_, hash, mask := task(r, protocol.SHA3, 2)
+		mine(protocol.SHA3, hash, append([]byte(nil), mask...))
+	}
+}
+
+func BenchmarkPool(b *testing.B) {
+	r := rand.New(rand.NewSource(3))
+	pool := NewPool(0)
+	hashes := uint64(0)
+	for i := 0; i < b.N; i++ {
+		_, hash, mask := task(r, protocol.SHA3, 2)
+		result, err := pool.Mine(context.Background(), protocol.SHA3, hash, mask)
+		if err != nil {
+			b.Fatal("Error mining the secret :", err)
+		}
+		hashes += result.Hashes
+	}
+	b.ReportMetric(float64(hashes)/b.Elapsed().Seconds(), "H/s")
+}
@@ -0,0 +1,178 @@
+package miner
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"republicofminer-client-go/protocol"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// StatsFile is the name of the statistics in the data directory
+const StatsFile = "stats.json"
+
+// SummaryInterval is the time between two summaries of the statistics in the logs
+var SummaryInterval = 5 * time.Minute
+
+// ResourceStats is what we mined of a resource
+type ResourceStats struct {
+	Tasks  int64
+	Solved int64
+	// Hashes counts the hashes of every search, the exhausted and abandoned ones included
+	Hashes uint64
+	// SearchTime is the total time spent searching the secrets, found or not
+	SearchTime time.Duration
+	// SolveTime is the total time spent searching the secrets that were found
+	SolveTime time.Duration
+	// Claimed is the amount of the claims sent to the explorer or the game server, in the smallest unit like the tasks
+	Claimed float64
+	// Confirmed is the amount of the claims confirmed in the ledgers, in the smallest unit like the tasks
+	Confirmed float64
+}
+
+// Hashrate is the average number of hashes per second while searching the secrets
+func (stats *ResourceStats) Hashrate() float64 {
+	if stats.SearchTime <= 0 {
+		return 0
+	}
+	return float64(stats.Hashes) / stats.SearchTime.Seconds()
+}
+
+// AverageSolveTime is the average time to find the secret of a task
+func (stats *ResourceStats) AverageSolveTime() time.Duration {
+	if stats.Solved == 0 {
+		return 0
+	}
+	return stats.SolveTime / time.Duration(stats.Solved)
+}
+
+// Stats records the mining statistics of each resource, on disk when it has a data directory
+type Stats struct {
+	mutex     sync.Mutex
+	path      string
+	resources map[string]*ResourceStats
+}
+
+// OpenStats loads the statistics of the data directory, they are only kept in memory when the directory is empty
+func OpenStats(dir string) (*Stats, error) {
+	stats := &Stats{resources: make(map[string]*ResourceStats)}
+	if dir == "" {
+		return stats, nil
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	stats.path = filepath.Join(dir, StatsFile)
+	if err := load(stats.path, &stats.resources); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// Received counts a new task
+func (stats *Stats) Received(resource string) error {
+	return stats.change(resource, func(resource *ResourceStats) {
+		resource.Tasks++
+	})
+}
+
+// Solved counts the work it took to find a secret
+func (stats *Stats) Solved(resource string, result *Result) error {
+	return stats.change(resource, func(resource *ResourceStats) {
+		resource.Solved++
+		resource.Hashes += result.Hashes
+		resource.SearchTime += result.Duration
+		resource.SolveTime += result.Duration
+	})
+}
+
+// Failed counts the work of a search that did not find the secret
+func (stats *Stats) Failed(resource string, result *Result) error {
+	return stats.change(resource, func(resource *ResourceStats) {
+		resource.Hashes += result.Hashes
+		resource.SearchTime += result.Duration
+	})
+}
+
+// Claimed adds the amount of a claim sent
+func (stats *Stats) Claimed(resource string, amount float64) error {
+	return stats.change(resource, func(resource *ResourceStats) {
+		resource.Claimed += amount
+	})
+}
+
+// Confirmed adds the amount of a claim confirmed
+func (stats *Stats) Confirmed(resource string, amount float64) error {
+	return stats.change(resource, func(resource *ResourceStats) {
+		resource.Confirmed += amount
+	})
+}
+
+func (stats *Stats) change(resource string, change func(resource *ResourceStats)) error {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+
+	current, ok := stats.resources[resource]
+	if !ok {
+		current = &ResourceStats{}
+		stats.resources[resource] = current
+	}
+	change(current)
+
+	if stats.path == "" {
+		return nil
+	}
+	return store(stats.path, stats.resources)
+}
+
+// Resources returns a copy of the statistics of each resource
+func (stats *Stats) Resources() map[string]ResourceStats {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+
+	resources := make(map[string]ResourceStats, len(stats.resources))
+	for resource, current := range stats.resources {
+		resources[resource] = *current
+	}
+	return resources
+}
+
+// Summary formats the statistics as a table with a line per resource and the total
+func (stats *Stats) Summary() string {
+	resources := stats.Resources()
+	names := make([]string, 0, len(resources))
+	for name := range resources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buffer bytes.Buffer
+	writer := tabwriter.NewWriter(&buffer, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(writer, "resource\ttasks\tsolved\thashes\tavg solve time\thashrate\tclaimed\tconfirmed\t")
+
+	var total ResourceStats
+	line := func(name string, stats *ResourceStats) {
+		fmt.Fprintf(writer, "%s\t%d\t%d\t%d\t%v\t%.0f H/s\t%.8g\t%.8g\t\n",
+			name, stats.Tasks, stats.Solved, stats.Hashes, stats.AverageSolveTime().Round(time.Millisecond), stats.Hashrate(), protocol.Amount(stats.Claimed).ToFloat(), protocol.Amount(stats.Confirmed).ToFloat())
+	}
+	for _, name := range names {
+		current := resources[name]
+		line(name, &current)
+		total.Tasks += current.Tasks
+		total.Solved += current.Solved
+		total.Hashes += current.Hashes
+		total.SearchTime += current.SearchTime
+		total.SolveTime += current.SolveTime
+	}
+	// the amounts of different resources do not add up
+	fmt.Fprintf(writer, "total\t%d\t%d\t%d\t%v\t%.0f H/s\t\t\t\n",
+		total.Tasks, total.Solved, total.Hashes, total.AverageSolveTime().Round(time.Millisecond), total.Hashrate())
+
+	writer.Flush()
+	return buffer.String()
+}
@@ -0,0 +1,49 @@
+package miner
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	dir := t.TempDir()
+	stats, err := OpenStats(dir)
+	if err != nil {
+		t.Fatal("Error opening the statistics :", err)
+	}
+
+	stats.Received("WOD")
+	stats.Received("WOD")
+	stats.Received("IRO")
+	stats.Solved("WOD", &Result{Hashes: 3000, Duration: time.Second})
+	stats.Solved("WOD", &Result{Hashes: 1000, Duration: time.Second})
+	stats.Failed("IRO", &Result{Hashes: 500, Duration: time.Second})
+	// the amounts are in the smallest unit like the tasks
+	stats.Claimed("WOD", 250000000)
+	stats.Confirmed("WOD", 125000000)
+
+	// the history survives a restart
+	reopened, err := OpenStats(dir)
+	if err != nil {
+		t.Fatal("Error reopening the statistics :", err)
+	}
+	wod := reopened.Resources()["WOD"]
+	if wod.Tasks != 2 || wod.Solved != 2 || wod.Hashes != 4000 || wod.Claimed != 250000000 || wod.Confirmed != 125000000 {
+		t.Fatal("unexpected statistics :", wod)
+	}
+	if wod.Hashrate() != 2000 || wod.AverageSolveTime() != time.Second {
+		t.Fatal("expected : 2000 H/s in 1s actual", wod.Hashrate(), wod.AverageSolveTime())
+	}
+	// the failed searches count in the hashrate but not in the solve time
+	if iro := reopened.Resources()["IRO"]; iro.Tasks != 1 || iro.Solved != 0 || iro.Hashes != 500 || iro.Hashrate() != 500 || iro.AverageSolveTime() != 0 {
+		t.Fatal("unexpected statistics :", iro)
+	}
+
+	summary := reopened.Summary()
+	for _, expected := range []string{"IRO", "WOD", "avg solve time", "2000 H/s", "500 H/s", "2.5", "1.25", "total"} {
+		if !strings.Contains(summary, expected) {
+			t.Fatal("the summary should contain", expected, ":\n", summary)
+		}
+	}
+}
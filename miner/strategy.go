@@ -0,0 +1,229 @@
+package miner
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"republicofminer-client-go/explorer"
+	"republicofminer-client-go/explorer/api"
+	"republicofminer-client-go/protocol"
+	game "republicofminer-client-go/republicofminer/api"
+	"sort"
+	"sync"
+)
+
+// Resources are the currencies the game server gives mining tasks for
+var Resources = []string{"WOD", "STN", "IRO"}
+
+// the names of the strategies in the Config
+const (
+	WeightedRandomStrategy = "random"
+	RoundRobinStrategy     = "round-robin"
+	InventoryStrategy      = "inventory"
+	ValueStrategy          = "value"
+)
+
+// Strategy decides which resource the miner asks a task for
+type Strategy interface {
+	// Next returns the resource of the next task
+	Next() (string, error)
+	// Observe is called once the secret of a task is found
+	Observe(resource string, task *game.MiningTask, result *Result)
+}
+
+// Accounts gives the balance of the wallet, it is implemented by the explorer
+type Accounts interface {
+	GetAccount(address string) (*api.GetAccountResponse, error)
+}
+
+// NewStrategy instanciates the strategy selected in the config
+func NewStrategy(config Config, accounts Accounts, address string) (Strategy, error) {
+	switch config.Strategy {
+	case "", WeightedRandomStrategy:
+		return NewWeightedRandom(config.Weights)
+	case RoundRobinStrategy:
+		return NewRoundRobin(Resources), nil
+	case InventoryStrategy:
+		return NewTargetInventory(accounts, address, config.Goals)
+	case ValueStrategy:
+		return NewValuePerSecond(Resources), nil
+	}
+	return nil, fmt.Errorf("%w : unknown strategy %q", ErrInvalidConfig, config.Strategy)
+}
+
+// WeightedRandom picks a resource at random in proportion of its weight
+type WeightedRandom struct {
+	resources []string
+	weights   []float64
+	total     float64
+}
+
+// NewWeightedRandom creates the strategy, every resource has the same weight when there is no weight
+func NewWeightedRandom(weights map[string]float64) (*WeightedRandom, error) {
+	if len(weights) == 0 {
+		weights = map[string]float64{}
+		for _, resource := range Resources {
+			weights[resource] = 1
+		}
+	}
+
+	strategy := &WeightedRandom{}
+	for resource := range weights {
+		strategy.resources = append(strategy.resources, resource)
+	}
+	// the map order is random, we want the same draw to give the same resource
+	sort.Strings(strategy.resources)
+	for _, resource := range strategy.resources {
+		weight := weights[resource]
+		if weight < 0 || math.IsNaN(weight) || math.IsInf(weight, 0) {
+			return nil, fmt.Errorf("%w : invalid weight %v for %s", ErrInvalidConfig, weight, resource)
+		}
+		strategy.weights = append(strategy.weights, weight)
+		strategy.total += weight
+	}
+	if strategy.total == 0 {
+		return nil, fmt.Errorf("%w : every weight is zero", ErrInvalidConfig)
+	}
+	return strategy, nil
+}
+
+func (strategy *WeightedRandom) Next() (string, error) {
+	return strategy.pick(rand.Float64()), nil
+}
+
+// pick returns the resource for a draw between 0 and 1
+func (strategy *WeightedRandom) pick(draw float64) string {
+	draw *= strategy.total
+	for index, weight := range strategy.weights {
+		if draw < weight {
+			return strategy.resources[index]
+		}
+		draw -= weight
+	}
+	// rounding errors
+	return strategy.resources[len(strategy.resources)-1]
+}
+
+func (strategy *WeightedRandom) Observe(resource string, task *game.MiningTask, result *Result) {}
+
+// RoundRobin mines the resources one after the other
+type RoundRobin struct {
+	mutex     sync.Mutex
+	resources []string
+	next      int
+}
+
+func NewRoundRobin(resources []string) *RoundRobin {
+	return &RoundRobin{resources: resources}
+}
+
+func (strategy *RoundRobin) Next() (string, error) {
+	strategy.mutex.Lock()
+	defer strategy.mutex.Unlock()
+	resource := strategy.resources[strategy.next]
+	strategy.next = (strategy.next + 1) % len(strategy.resources)
+	return resource, nil
+}
+
+func (strategy *RoundRobin) Observe(resource string, task *game.MiningTask, result *Result) {}
+
+// TargetInventory mines the resource whose balance is the furthest below its goal
+type TargetInventory struct {
+	accounts Accounts
+	address  string
+	goals    map[string]float64
+}
+
+func NewTargetInventory(accounts Accounts, address string, goals map[string]float64) (*TargetInventory, error) {
+	if len(goals) == 0 {
+		return nil, fmt.Errorf("%w : the inventory strategy needs a goal for each resource", ErrInvalidConfig)
+	}
+	return &TargetInventory{accounts: accounts, address: address, goals: goals}, nil
+}
+
+func (strategy *TargetInventory) Next() (string, error) {
+	balance := map[string]float64{}
+	account, err := strategy.accounts.GetAccount(strategy.address)
+	switch {
+	case err == nil:
+		balance = account.Balance
+	case errors.Is(err, explorer.ErrNotFound):
+		// the wallet did not receive anything yet
+	default:
+		return "", err
+	}
+
+	resources := make([]string, 0, len(strategy.goals))
+	for resource := range strategy.goals {
+		resources = append(resources, resource)
+	}
+	sort.Strings(resources)
+
+	// once every goal is reached, we keep mining the resource that is the least above its goal
+	best := ""
+	missing := math.Inf(-1)
+	for _, resource := range resources {
+		if difference := strategy.goals[resource] - balance[resource]; difference > missing {
+			best = resource
+			missing = difference
+		}
+	}
+	return best, nil
+}
+
+func (strategy *TargetInventory) Observe(resource string, task *game.MiningTask, result *Result) {}
+
+// Exploration is the probability that the value strategy mines another resource than the best one to refresh its estimation
+var Exploration = 0.1
+
+// ValuePerSecond mines the resource that earns the most per second
+// the value is the amount of the last task divided by the time we expect to find its secret at the observed hashrate
+type ValuePerSecond struct {
+	mutex     sync.Mutex
+	resources []string
+	values    map[string]float64
+	explore   *RoundRobin
+}
+
+func NewValuePerSecond(resources []string) *ValuePerSecond {
+	return &ValuePerSecond{resources: resources, values: map[string]float64{}, explore: NewRoundRobin(resources)}
+}
+
+func (strategy *ValuePerSecond) Next() (string, error) {
+	strategy.mutex.Lock()
+	defer strategy.mutex.Unlock()
+
+	// we need a task of every resource before we can compare them
+	for _, resource := range strategy.resources {
+		if _, ok := strategy.values[resource]; !ok {
+			return resource, nil
+		}
+	}
+	if rand.Float64() < Exploration {
+		return strategy.explore.Next()
+	}
+
+	best := strategy.resources[0]
+	for _, resource := range strategy.resources[1:] {
+		if strategy.values[resource] > strategy.values[best] {
+			best = resource
+		}
+	}
+	return best, nil
+}
+
+func (strategy *ValuePerSecond) Observe(resource string, task *game.MiningTask, result *Result) {
+	mask, err := base64.StdEncoding.DecodeString(task.Mask)
+	if err != nil || result.Hashrate() <= 0 {
+		return
+	}
+	// on average, we find the secret after trying half of the unknown secrets
+	unknown := protocol.SECRET_SIZE - len(mask)
+	expected := math.Pow(256, float64(unknown)) / 2 / result.Hashrate()
+
+	strategy.mutex.Lock()
+	strategy.values[resource] = task.Amount / expected
+	strategy.mutex.Unlock()
+}
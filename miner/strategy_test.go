@@ -0,0 +1,122 @@
+package miner
+
+import (
+	"encoding/base64"
+	"errors"
+	"republicofminer-client-go/explorer"
+	"republicofminer-client-go/explorer/api"
+	game "republicofminer-client-go/republicofminer/api"
+	"testing"
+	"time"
+)
+
+type accounts struct {
+	balance map[string]float64
+	err     error
+}
+
+func (accounts *accounts) GetAccount(address string) (*api.GetAccountResponse, error) {
+	if accounts.err != nil {
+		return nil, accounts.err
+	}
+	return &api.GetAccountResponse{Address: address, Balance: accounts.balance}, nil
+}
+
+func TestWeightedRandom(t *testing.T) {
+	strategy, err := NewWeightedRandom(map[string]float64{"IRO": 1, "STN": 0, "WOD": 3})
+	if err != nil {
+		t.Fatal("Error creating the strategy :", err)
+	}
+	for draw, expected := range map[float64]string{0: "IRO", 0.2: "IRO", 0.25: "WOD", 0.99: "WOD"} {
+		if actual := strategy.pick(draw); actual != expected {
+			t.Fatal("draw", draw, "expected :", expected, "actual", actual)
+		}
+	}
+
+	if _, err := NewWeightedRandom(map[string]float64{"IRO": 0}); err == nil {
+		t.Fatal("the weights cannot all be zero")
+	}
+	if _, err := NewWeightedRandom(map[string]float64{"IRO": -1, "WOD": 2}); err == nil {
+		t.Fatal("the weights cannot be negative")
+	}
+}
+
+func TestRoundRobin(t *testing.T) {
+	strategy := NewRoundRobin(Resources)
+	for index := 0; index < 2*len(Resources); index++ {
+		if actual, _ := strategy.Next(); actual != Resources[index%len(Resources)] {
+			t.Fatal("expected :", Resources[index%len(Resources)], "actual", actual)
+		}
+	}
+}
+
+func TestTargetInventory(t *testing.T) {
+	goals := map[string]float64{"WOD": 100, "STN": 50, "IRO": 10}
+	wallet := &accounts{balance: map[string]float64{"WOD": 90, "STN": 20, "IRO": 0}}
+	strategy, _ := NewTargetInventory(wallet, "address", goals)
+
+	if actual, _ := strategy.Next(); actual != "STN" {
+		t.Fatal("expected : STN actual", actual)
+	}
+
+	// every goal is reached
+	wallet.balance = map[string]float64{"WOD": 120, "STN": 51, "IRO": 30}
+	if actual, _ := strategy.Next(); actual != "STN" {
+		t.Fatal("expected : STN actual", actual)
+	}
+
+	// the account does not exist before the first reward
+	wallet.err = explorer.ErrNotFound
+	if actual, err := strategy.Next(); err != nil || actual != "WOD" {
+		t.Fatal("expected : WOD actual", actual, err)
+	}
+
+	wallet.err = explorer.ErrTimeout
+	if _, err := strategy.Next(); !errors.Is(err, explorer.ErrTimeout) {
+		t.Fatal("expected :", explorer.ErrTimeout, "actual", err)
+	}
+}
+
+func TestValuePerSecond(t *testing.T) {
+	defer func(exploration float64) { Exploration = exploration }(Exploration)
+	Exploration = 0
+
+	strategy := NewValuePerSecond(Resources)
+	result := &Result{Hashes: 1000000, Duration: time.Second}
+	mask := func(size int) string { return base64.StdEncoding.EncodeToString(make([]byte, size)) }
+
+	// every resource is tried first
+	observed := map[string]*game.MiningTask{
+		"WOD": &game.MiningTask{Mask: mask(29), Amount: 10},
+		"STN": &game.MiningTask{Mask: mask(30), Amount: 1},
+		"IRO": &game.MiningTask{Mask: mask(29), Amount: 20},
+	}
+	for range Resources {
+		resource, _ := strategy.Next()
+		task, ok := observed[resource]
+		if !ok {
+			t.Fatal("the resource was already tried :", resource)
+		}
+		delete(observed, resource)
+		strategy.Observe(resource, task, result)
+	}
+
+	// STN is 256 times easier for a tenth of the amount of WOD
+	if actual, _ := strategy.Next(); actual != "STN" {
+		t.Fatal("expected : STN actual", actual)
+	}
+}
+
+func TestNewStrategy(t *testing.T) {
+	for _, name := range []string{"", WeightedRandomStrategy, RoundRobinStrategy, ValueStrategy} {
+		if _, err := NewStrategy(Config{Strategy: name}, nil, ""); err != nil {
+			t.Fatal("Error creating the strategy", name, ":", err)
+		}
+	}
+	if _, err := NewStrategy(Config{Strategy: InventoryStrategy}, nil, ""); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatal("the inventory strategy needs goals")
+	}
+	if _, err := NewStrategy(Config{Strategy: "unknown"}, nil, ""); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatal("the strategy is unknown")
+	}
+}
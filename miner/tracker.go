@@ -0,0 +1,245 @@
+package miner
+
+import (
+	"errors"
+	"log"
+	"republicofminer-client-go/explorer"
+	"republicofminer-client-go/explorer/api"
+	"republicofminer-client-go/protocol"
+	"time"
+)
+
+// ClaimExpiry is the time a claim transaction can wait for a ledger before it expires
+var ClaimExpiry = 10 * time.Minute
+
+// CheckInterval is the time between two checks of the submitted claims
+var CheckInterval = 30 * time.Second
+
+// MaxAttempts is the number of claims of a task rejected by the explorer before we abandon it
+// the failures of the connections are retried without limit
+var MaxAttempts = 5
+
+// DefaultConfirmations is the number of ledgers closed after the one including a claim before it is confirmed
+const DefaultConfirmations = 3
+
+// track follows the claims in the ledgers and resubmits the ones that failed or expired
+func (miner *Miner) track() {
+	// the ledgers are handled in this goroutine, we skip them when we are late
+	ledgers := make(chan *api.Ledger, 16)
+	unsubscribe := miner.explorer.OnLedger(func(ledger *api.Ledger) {
+		select {
+		case ledgers <- ledger:
+		default:
+		}
+	})
+	defer unsubscribe()
+
+	ticker := time.NewTicker(CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case ledger := <-ledgers:
+			miner.ledger(ledger)
+		case <-ticker.C:
+			miner.check()
+		}
+	}
+}
+
+// ledger moves the claims included in the ledger and the claims confirmed by it
+func (miner *Miner) ledger(ledger *api.Ledger) {
+	miner.mutex.Lock()
+	defer miner.mutex.Unlock()
+
+	if ledger.Height > miner.height {
+		miner.height = ledger.Height
+	}
+
+	hashes := make(map[string]bool, len(ledger.Transactions))
+	for _, header := range ledger.Transactions {
+		hashes[header.Hash] = true
+	}
+
+	for _, claim := range miner.journal.Claims(Submitted) {
+		if hashes[claim.Transaction] {
+			claim.State = Included
+			claim.Height = ledger.Height
+			miner.update(claim)
+			log.Println("Claim included in ledger", ledger.Height, ":", claim.Transaction)
+		}
+	}
+	miner.confirm()
+}
+
+// confirm moves the included claims followed by enough ledgers, the caller must hold the mutex
+func (miner *Miner) confirm() {
+	for _, claim := range miner.journal.Claims(Included) {
+		if miner.height-claim.Height >= miner.confirmations {
+			claim.State = Confirmed
+			miner.update(claim)
+			log.Println("Claim confirmed :", claim.Transaction)
+			miner.record(miner.stats.Confirmed(claim.Resource, claim.Task.Amount))
+		}
+	}
+}
+
+// check follows the chain and the submitted claims with the requests by height and by hash, then resubmits the failed and expired claims
+// the ledger notifications and the last ledger are only shortcuts, the tracking does not depend on them
+func (miner *Miner) check() {
+	if last, err := miner.explorer.GetLedger(&api.GetLedgerRequest{}); err == nil {
+		miner.ledger(last)
+	}
+
+	miner.mutex.Lock()
+	known := miner.height
+	miner.mutex.Unlock()
+	height, err := miner.latest(known)
+	if err != nil {
+		log.Println("Get last ledger failed :", err)
+	}
+
+	miner.mutex.Lock()
+	if height > miner.height {
+		miner.height = height
+	}
+	miner.confirm()
+	claims := miner.journal.Claims(Solved, Submitted)
+	miner.mutex.Unlock()
+
+	now := time.Now().Unix()
+	for _, claim := range claims {
+		if claim.State == Submitted {
+			// the transaction may be in a ledger we did not see
+			_, err := miner.explorer.GetTransaction(claim.Transaction)
+			if err == nil {
+				miner.included(claim)
+				continue
+			}
+			if !errors.Is(err, explorer.ErrNotFound) {
+				// we cannot tell, the next check asks again
+				log.Println("Get claim transaction failed :", err)
+				continue
+			}
+			if now <= claim.Expire {
+				continue
+			}
+			log.Println("Claim expired :", claim.Transaction)
+		}
+		miner.submit(claim)
+	}
+}
+
+// included moves the submitted claim the explorer knows, the ledger including it is at most the last one we know
+func (miner *Miner) included(claim *Claim) {
+	miner.mutex.Lock()
+	defer miner.mutex.Unlock()
+
+	current, ok := miner.journal.Claim(claim.Task.Address)
+	if !ok || current.State != Submitted || current.Transaction != claim.Transaction {
+		return
+	}
+	current.State = Included
+	current.Height = miner.height
+	miner.update(current)
+	log.Println("Claim included :", current.Transaction)
+	miner.confirm()
+}
+
+// latest finds the height of the last ledger with the requests by height, from the height we know
+func (miner *Miner) latest(known int64) (int64, error) {
+	exists := func(height int64) (bool, error) {
+		_, err := miner.explorer.GetLedgerByHeight(height)
+		if errors.Is(err, explorer.ErrNotFound) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	// the step doubles until a ledger is missing, then we search between the last ledger found and the missing one
+	found, missing := known, int64(-1)
+	for step := int64(1); missing < 0; step *= 2 {
+		ok, err := exists(found + step)
+		if err != nil {
+			return found, err
+		}
+		if ok {
+			found += step
+		} else {
+			missing = found + step
+		}
+	}
+	for missing-found > 1 {
+		middle := found + (missing-found)/2
+		ok, err := exists(middle)
+		if err != nil {
+			return found, err
+		}
+		if ok {
+			found = middle
+		} else {
+			missing = middle
+		}
+	}
+	return found, nil
+}
+
+// submit sends the claim of a solved task, the mutex is only held to change the claim
+func (miner *Miner) submit(claim *Claim) {
+	miner.mutex.Lock()
+	// the claim is being sent, or it changed since we read it
+	current, ok := miner.journal.Claim(claim.Task.Address)
+	if miner.submitting[claim.Task.Address] || !ok || current.State != claim.State || current.Transaction != claim.Transaction {
+		miner.mutex.Unlock()
+		return
+	}
+	miner.submitting[claim.Task.Address] = true
+	miner.mutex.Unlock()
+
+	var txhash string
+	secret, err := protocol.RevealSecret(TaskSecretHashType, protocol.SecretFromBase64(claim.Secret))
+	if err == nil {
+		txhash, err = miner.claim(claim.Task, secret)
+	}
+
+	miner.mutex.Lock()
+	defer miner.mutex.Unlock()
+	delete(miner.submitting, claim.Task.Address)
+	// a previous transaction of the claim was included while we were sending this one
+	if current, ok := miner.journal.Claim(claim.Task.Address); !ok || current.State != claim.State {
+		return
+	}
+
+	if err != nil {
+		log.Println("Claim failed :", err)
+		claim.State = Solved
+		claim.Error = err.Error()
+		if errors.Is(err, explorer.ErrRejected) {
+			claim.Attempts++
+		}
+		if claim.Attempts >= MaxAttempts {
+			log.Println("Claim abandoned after", claim.Attempts, "rejections, the secret stays in the journal :", claim.Task.Address, claim.Error)
+			claim.State = Abandoned
+		}
+		miner.update(claim)
+		return
+	}
+
+	log.Println("Claim transaction sent :", txhash)
+	// the resubmissions claim the same reward
+	if claim.Transaction == "" {
+		miner.record(miner.stats.Claimed(claim.Resource, claim.Task.Amount))
+	}
+	claim.State = Submitted
+	claim.Transaction = txhash
+	claim.Expire = time.Now().Add(ClaimExpiry).Unix()
+	claim.Error = ""
+	miner.update(claim)
+}
+
+// update saves the claim in the journal, we keep mining when the journal cannot be written
+func (miner *Miner) update(claim *Claim) {
+	if err := miner.journal.Update(claim); err != nil {
+		log.Println("Save claim journal failed :", err)
+	}
+}
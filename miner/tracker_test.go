@@ -0,0 +1,125 @@
+package miner
+
+import (
+	"encoding/json"
+	"republicofminer-client-go/common/websocket/websockettest"
+	"republicofminer-client-go/explorer"
+	"republicofminer-client-go/protocol"
+	game "republicofminer-client-go/republicofminer/api"
+	"republicofminer-client-go/wallet"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// the explorer answers the ledgers by height and the transactions by hash, but neither the last ledger nor the notifications
+func TestCheckFollowsLedgersByHeight(t *testing.T) {
+	var head int64 = 12
+	var miner *Miner
+	unlocked := func() {
+		// the requests are sent without the mutex
+		if !miner.mutex.TryLock() {
+			t.Error("the mutex is held during a request")
+			return
+		}
+		miner.mutex.Unlock()
+	}
+
+	server := websockettest.Server(t, map[string]websockettest.Responder{
+		"GetLedgerRequest": func(data json.RawMessage) []websockettest.Message {
+			unlocked()
+			var request struct{ Height *int64 }
+			json.Unmarshal(data, &request)
+			if request.Height == nil || *request.Height > atomic.LoadInt64(&head) {
+				return websockettest.Response("GetLedgerResponse", map[string]interface{}{}, 0)
+			}
+			return websockettest.Response("GetLedgerResponse", map[string]interface{}{"Ledger": map[string]interface{}{"Height": *request.Height, "Hash": "ledger"}}, 0)
+		},
+		"GetTransactionRequest": func(data json.RawMessage) []websockettest.Message {
+			unlocked()
+			return websockettest.Response("GetTransactionResponse", map[string]interface{}{"Transaction": map[string]interface{}{"Hash": "claim"}}, 0)
+		},
+	})
+	defer server.Close()
+
+	client := explorer.New(explorer.Options{Endpoint: websockettest.Address(server), Timeout: time.Second})
+	go client.Connect()
+	defer client.Close()
+
+	journal, _ := OpenJournal("")
+	stats, _ := OpenStats("")
+	miner = &Miner{explorer: client, journal: journal, stats: stats, confirmations: 2, submitting: map[string]bool{}}
+	journal.Update(&Claim{Resource: "WOD", Task: &game.MiningTask{Address: "task", Amount: 1.5}, State: Submitted, Transaction: "claim", Expire: time.Now().Add(time.Hour).Unix()})
+
+	miner.check()
+	claims := journal.Claims(Included)
+	if len(claims) != 1 || claims[0].Height != 12 {
+		t.Fatal("the claim should be included at the last ledger 12, actual", claims)
+	}
+
+	atomic.StoreInt64(&head, 14)
+	miner.check()
+	if len(journal.Claims(Submitted, Included)) != 0 || stats.Resources()["WOD"].Confirmed != 1.5 {
+		t.Fatal("the claim should be confirmed two ledgers later")
+	}
+}
+
+// only the rejections of the explorer count toward MaxAttempts, the failures of the connection are retried
+func TestSubmitCountsRejections(t *testing.T) {
+	var rejected, sent, unknown int32
+	server := websockettest.Server(t, map[string]websockettest.Responder{
+		"SendTransactionRequest": func(json.RawMessage) []websockettest.Message {
+			atomic.AddInt32(&sent, 1)
+			if atomic.LoadInt32(&rejected) == 0 {
+				// the explorer is down, the request times out
+				return nil
+			}
+			return websockettest.Response("SendTransactionResponse", map[string]interface{}{}, 4)
+		},
+		"GetTransactionRequest": func(json.RawMessage) []websockettest.Message {
+			if atomic.LoadInt32(&unknown) == 0 {
+				return websockettest.Response("GetTransactionResponse", map[string]interface{}{}, 1)
+			}
+			return websockettest.Response("GetTransactionResponse", map[string]interface{}{}, 0)
+		},
+		"GetLedgerRequest": websockettest.Respond("GetLedgerResponse", map[string]interface{}{}, 0),
+	})
+	defer server.Close()
+
+	client := explorer.New(explorer.Options{Endpoint: websockettest.Address(server), Timeout: 100 * time.Millisecond})
+	go client.Connect()
+	defer client.Close()
+
+	key := protocol.GeneratePrivateKey()
+	account := &wallet.Wallet{Privatekey: key, Publickey: key.GetPublicKey(), Address: key.GetPublicKey().GetAddress()}
+	journal, _ := OpenJournal("")
+	stats, _ := OpenStats("")
+	miner := &Miner{explorer: client, wallet: account, journal: journal, stats: stats, submitting: map[string]bool{}}
+	task := &game.MiningTask{Address: protocol.GeneratePrivateKey().GetPublicKey().GetAddress().Encoded, Currency: "WOD", Amount: 150000000}
+	secret := make(protocol.Secret, protocol.SECRET_SIZE)
+
+	// an expired claim is not resubmitted while the explorer cannot tell if it knows the transaction
+	journal.Update(&Claim{Resource: "WOD", Task: task, State: Submitted, Secret: secret.ToBase64(), Transaction: "claim", Expire: 1})
+	miner.check()
+	if atomic.LoadInt32(&sent) != 0 || len(journal.Claims(Submitted)) != 1 {
+		t.Fatal("the claim should wait for an answer of the explorer")
+	}
+
+	atomic.StoreInt32(&unknown, 1)
+	for attempt := 0; attempt < MaxAttempts+1; attempt++ {
+		miner.check()
+	}
+	claims := journal.Claims(Solved)
+	if len(claims) != 1 || claims[0].Attempts != 0 {
+		t.Fatal("the claims that time out should not count, actual", claims)
+	}
+
+	atomic.StoreInt32(&rejected, 1)
+	for attempt := 0; attempt < MaxAttempts; attempt++ {
+		miner.check()
+	}
+	claims = journal.Claims(Abandoned)
+	if len(claims) != 1 || claims[0].Attempts != MaxAttempts || claims[0].Secret != secret.ToBase64() {
+		t.Fatal("the rejected claim should be abandoned with its secret, actual", claims)
+	}
+}
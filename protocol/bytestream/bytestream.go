@@ -1,58 +1,108 @@
 package bytestream
 
-import "reflect"
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// MaxListLength is the maximum number of elements of a list, the length is written in one byte
+const MaxListLength = 255
+
+// ErrListTooLong is returned when a list has more elements than MaxListLength
+var ErrListTooLong = errors.New("bytestream: list too long")
+
+// ErrEndOfStream is returned when the binary is shorter than the data to read
+var ErrEndOfStream = errors.New("bytestream: unexpected end of stream")
+
+// ErrInvalidNullable is returned when the nullable flag is neither 0 nor 1
+var ErrInvalidNullable = errors.New("bytestream: invalid nullable flag")
+
+// ErrTrailingBytes is returned when the binary is longer than the data read
+var ErrTrailingBytes = errors.New("bytestream: unexpected trailing bytes")
 
 type ByteStream struct {
 	buffer []byte
 	index  int
+	err    error
 }
 
-// Write serializes the data and returns the binary
-func Write(data ByteStreamer) []byte {
-	// TODO automaticaly resize the buffer
-	stream := &ByteStream{make([]byte, 1024), 0}
+// Write serializes the data and returns the binary, the buffer grows as needed
+// it fails when the data cannot be encoded in the binary format
+func Write(data ByteStreamer) ([]byte, error) {
+	stream := &ByteStream{buffer: make([]byte, 0, 256)}
 	data.Write(stream)
-	return stream.buffer[:stream.index]
+	if stream.err != nil {
+		return nil, stream.err
+	}
+	return stream.buffer, nil
+}
+
+// Read deserializes the binary into the data, the whole binary must be consumed
+func Read(bytes []byte, data ByteStreamReader) error {
+	stream := &ByteStream{buffer: bytes}
+	if err := data.Read(stream); err != nil {
+		return err
+	}
+	if stream.Remaining() != 0 {
+		return ErrTrailingBytes
+	}
+	return nil
 }
 
 type ByteStreamer interface {
 	Write(stream *ByteStream)
 }
 
+type ByteStreamReader interface {
+	Read(stream *ByteStream) error
+}
+
+// Fail stops the serialization, the next writes are ignored and Write returns the error
+func (stream *ByteStream) Fail(err error) {
+	if stream.err == nil {
+		stream.err = err
+	}
+}
+
+// Err returns the error that stopped the serialization
+func (stream *ByteStream) Err() error {
+	return stream.err
+}
+
 func (stream *ByteStream) WriteByte(b byte) error {
-	stream.buffer[stream.index] = b
-	stream.index++
+	if stream.err != nil {
+		return stream.err
+	}
+	stream.buffer = append(stream.buffer, b)
 	return nil
 }
 
 func (stream *ByteStream) WriteBytes(bytes []byte) {
-	for _, b := range bytes {
-		stream.WriteByte(b)
+	if stream.err != nil {
+		return
 	}
+	stream.buffer = append(stream.buffer, bytes...)
 }
 
+// the integers are written in little endian two's complement like the .NET BinaryWriter
 func (stream *ByteStream) WriteInt64(i int64) {
-	shift := int64(256)
-	for index := 0; index < 8; index++ {
-		stream.WriteByte(byte(i % shift))
-		i /= shift
-	}
+	buffer := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buffer, uint64(i))
+	stream.WriteBytes(buffer)
 }
 
 func (stream *ByteStream) WriteInt32(i int32) {
-	shift := int32(256)
-	for index := 0; index < 4; index++ {
-		stream.WriteByte(byte(i % shift))
-		i /= shift
-	}
+	buffer := make([]byte, 4)
+	binary.LittleEndian.PutUint32(buffer, uint32(i))
+	stream.WriteBytes(buffer)
 }
 
 func (stream *ByteStream) WriteInt16(i int16) {
-	shift := int16(256)
-	for index := 0; index < 2; index++ {
-		stream.WriteByte(byte(i % shift))
-		i /= shift
-	}
+	buffer := make([]byte, 2)
+	binary.LittleEndian.PutUint16(buffer, uint16(i))
+	stream.WriteBytes(buffer)
 }
 
 func (stream *ByteStream) WriteString(s string) {
@@ -104,12 +154,93 @@ func (stream *ByteStream) WriteNullable(data interface{}) {
 }
 
 func (stream *ByteStream) WriteList(length int, callback func(i int) ByteStreamer) {
+	if length > MaxListLength {
+		stream.Fail(fmt.Errorf("%w: %d elements", ErrListTooLong, length))
+		return
+	}
 	stream.WriteByte(byte(length))
 	for index := 0; index < length; index++ {
 		callback(index).Write(stream)
 	}
 }
 
+func (stream *ByteStream) ReadByte() (byte, error) {
+	if stream.Remaining() < 1 {
+		return 0, ErrEndOfStream
+	}
+	b := stream.buffer[stream.index]
+	stream.index++
+	return b, nil
+}
+
+func (stream *ByteStream) ReadBytes(length int) ([]byte, error) {
+	if length < 0 || stream.Remaining() < length {
+		return nil, ErrEndOfStream
+	}
+	bytes := make([]byte, length)
+	copy(bytes, stream.buffer[stream.index:])
+	stream.index += length
+	return bytes, nil
+}
+
+func (stream *ByteStream) ReadInt64() (int64, error) {
+	bytes, err := stream.ReadBytes(8)
+	if err != nil {
+		return 0, err
+	}
+	return int64(binary.LittleEndian.Uint64(bytes)), nil
+}
+
+func (stream *ByteStream) ReadInt32() (int32, error) {
+	bytes, err := stream.ReadBytes(4)
+	if err != nil {
+		return 0, err
+	}
+	return int32(binary.LittleEndian.Uint32(bytes)), nil
+}
+
+func (stream *ByteStream) ReadInt16() (int16, error) {
+	bytes, err := stream.ReadBytes(2)
+	if err != nil {
+		return 0, err
+	}
+	return int16(binary.LittleEndian.Uint16(bytes)), nil
+}
+
+// ReadNullable reads the nullable flag and calls the callback when there is a value
+func (stream *ByteStream) ReadNullable(callback func() error) (bool, error) {
+	flag, err := stream.ReadByte()
+	if err != nil {
+		return false, err
+	}
+	switch flag {
+	case 0:
+		return false, nil
+	case 1:
+		return true, callback()
+	}
+	return false, ErrInvalidNullable
+}
+
+// ReadList reads the length of the list and calls the callback for every element
+func (stream *ByteStream) ReadList(callback func(i int) error) error {
+	length, err := stream.ReadByte()
+	if err != nil {
+		return err
+	}
+	for index := 0; index < int(length); index++ {
+		if err := callback(index); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Remaining returns the number of bytes left to read
+func (stream *ByteStream) Remaining() int {
+	return len(stream.buffer) - stream.index
+}
+
 func (stream *ByteStream) Buffer() []byte {
 	return stream.buffer
 }
@@ -0,0 +1,109 @@
+package bytestream
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+type integers struct {
+	i64 int64
+	i32 int32
+	i16 int16
+	b   byte
+}
+
+func (data *integers) Write(stream *ByteStream) {
+	stream.WriteInt64(data.i64)
+	stream.WriteInt32(data.i32)
+	stream.WriteInt16(data.i16)
+	stream.WriteByte(data.b)
+}
+
+func (data *integers) Read(stream *ByteStream) (err error) {
+	if data.i64, err = stream.ReadInt64(); err != nil {
+		return err
+	}
+	if data.i32, err = stream.ReadInt32(); err != nil {
+		return err
+	}
+	if data.i16, err = stream.ReadInt16(); err != nil {
+		return err
+	}
+	data.b, err = stream.ReadByte()
+	return err
+}
+
+func TestIntegers(t *testing.T) {
+	values := []integers{
+		{0, 0, 0, 0},
+		{1, 1, 1, 1},
+		{-1, -1, -1, 255},
+		{1560404881, 100000000, 17575, 0x7f},
+		{-9223372036854775808, -2147483648, -32768, 0x80},
+	}
+
+	for _, expected := range values {
+		actual := integers{}
+		serialized, err := Write(&expected)
+		if err != nil {
+			t.Fatal("Error writing the integers :", err)
+		}
+		err = Read(serialized, &actual)
+		if err != nil {
+			t.Fatal("Error reading the integers :", err)
+		}
+		if actual != expected {
+			fmt.Println("expected :", expected, "actual", actual)
+			t.Fail()
+		}
+	}
+}
+
+func TestLittleEndian(t *testing.T) {
+	expected := []byte{0x02, 0x01, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 0xff, 0xff, 0x01, 0, 0x07}
+	actual, _ := Write(&integers{0x0102, -1, 1, 7})
+	if fmt.Sprint(actual) != fmt.Sprint(expected) {
+		fmt.Println("expected :", expected, "actual", actual)
+		t.Fail()
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	serialized, _ := Write(&integers{1, 2, 3, 4})
+
+	if err := Read(serialized[:len(serialized)-1], &integers{}); err != ErrEndOfStream {
+		t.Fatal("expected :", ErrEndOfStream, "actual", err)
+	}
+
+	if err := Read(append(serialized, 0), &integers{}); err != ErrTrailingBytes {
+		t.Fatal("expected :", ErrTrailingBytes, "actual", err)
+	}
+
+	stream := &ByteStream{buffer: []byte{2}}
+	if _, err := stream.ReadNullable(func() error { return nil }); err != ErrInvalidNullable {
+		t.Fatal("expected :", ErrInvalidNullable, "actual", err)
+	}
+}
+
+type list int
+
+func (length list) Write(stream *ByteStream) {
+	stream.WriteList(int(length), func(i int) ByteStreamer { return &integers{int64(i), 0, 0, 0} })
+}
+
+func TestWriteList(t *testing.T) {
+	serialized, err := Write(list(MaxListLength))
+	if err != nil {
+		t.Fatal("Error writing the longest list :", err)
+	}
+	// the buffer grows past its initial capacity
+	if len(serialized) != 1+MaxListLength*15 {
+		t.Fatal("expected :", 1+MaxListLength*15, "actual", len(serialized))
+	}
+
+	_, err = Write(list(MaxListLength + 1))
+	if !errors.Is(err, ErrListTooLong) {
+		t.Fatal("expected :", ErrListTooLong, "actual", err)
+	}
+}
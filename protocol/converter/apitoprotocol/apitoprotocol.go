@@ -3,25 +3,59 @@ package apitoprotocol
 
 import (
 	"encoding/base64"
+	"errors"
+	"fmt"
 	"republicofminer-client-go/explorer/api"
 	"republicofminer-client-go/protocol"
-	"republicofminer-client-go/protocol/bytestream"
+	"strings"
 )
 
-func ToTransaction(transaction *api.Transaction) *protocol.Transaction {
+func ToTransaction(transaction *api.Transaction) (*protocol.Transaction, error) {
+	if transaction.Expire == nil {
+		return nil, errors.New("apitoprotocol: the transaction has no expiration")
+	}
+
 	declarations := make([]*protocol.TxDeclaration, len(transaction.Declarations))
 	for index, d := range transaction.Declarations {
-		declarations[index] = ToDeclaration(d)
+		declaration, err := ToDeclaration(d)
+		if err != nil {
+			return nil, err
+		}
+		declarations[index] = declaration
 	}
 
 	inputs := make([]*protocol.TxInput, len(transaction.Inputs))
 	for index, d := range transaction.Inputs {
-		inputs[index] = ToInput(d)
+		input, err := ToInput(d)
+		if err != nil {
+			return nil, err
+		}
+		inputs[index] = input
 	}
 
 	outputs := make([]*protocol.TxOutput, len(transaction.Outputs))
 	for index, d := range transaction.Outputs {
-		outputs[index] = ToOutput(d)
+		output, err := ToOutput(d)
+		if err != nil {
+			return nil, err
+		}
+		outputs[index] = output
+	}
+
+	// the fees are optional in the format, but not an empty one
+	var fees *protocol.TxInput
+	if transaction.Fees != nil {
+		converted, err := ToInput(transaction.Fees)
+		if err != nil {
+			return nil, err
+		}
+		fees = converted
+	}
+
+	// the api omits the empty message, the transaction has no message
+	var message protocol.TransactionMessage
+	if transaction.Message != "" {
+		message = protocol.TransactionMessage([]byte(transaction.Message))
 	}
 
 	return &protocol.Transaction{
@@ -29,55 +63,100 @@ func ToTransaction(transaction *api.Transaction) *protocol.Transaction {
 		Declarations: declarations,
 		Inputs:       inputs,
 		Outputs:      outputs,
-		Message:      protocol.TransactionMessage([]byte(transaction.Message)),
-		Fees:         ToInput(transaction.Fees),
-	}
+		Message:      message,
+		Fees:         fees,
+	}, nil
 }
 
-func ToDeclaration(transaction *api.TxDeclaration) *protocol.TxDeclaration {
-	var declaration bytestream.ByteStreamer
+func ToDeclaration(transaction *api.TxDeclaration) (*protocol.TxDeclaration, error) {
+	if transaction == nil {
+		return nil, errors.New("apitoprotocol: missing declaration")
+	}
+	var declaration protocol.Declaration
 
-	switch transaction.Type {
-	case protocol.TxMultiSignature:
-	case protocol.TxHashLock:
-	case protocol.TxSecret:
-		secret := transaction.Declaration.(api.SecretRevelation)
-		decoded, _ := base64.StdEncoding.DecodeString(secret.Secret)
+	switch d := transaction.Declaration.(type) {
+	case *api.MultiSignature:
+		signers := make([]protocol.Address, len(d.Signers))
+		for index, signer := range d.Signers {
+			signers[index] = *protocol.DecodeAddress(signer)
+		}
+		declaration = &protocol.MultiSignature{Signers: signers, Required: d.Required}
+	case *api.HashLock:
+		hash, err := base64.StdEncoding.DecodeString(d.SecretHash.Hash)
+		if err != nil {
+			return nil, err
+		}
+		declaration = &protocol.HashLockDeclaration{SecretHash: protocol.SecretHash{Type: d.SecretHash.Type, Hash: hash}}
+	case *api.SecretRevelation:
+		decoded, err := base64.StdEncoding.DecodeString(d.Secret)
+		if err != nil {
+			return nil, err
+		}
 		declaration = protocol.NewSecretRevelation(protocol.Secret(decoded))
-	case protocol.TxTimeLock:
-	case protocol.TxVendingMachine:
-	case protocol.TxLimitOrder:
-	case protocol.TxDelegatedAccount:
+	case *api.TimeLock:
+		declaration = &protocol.TimeLockDeclaration{Timestamp: d.Timestamp}
+	case *api.VendingMachine:
+		declaration = &protocol.VendingMachineDeclaration{
+			Owner:       *protocol.DecodeAddress(d.Owner),
+			CurrencyIn:  protocol.CurrencyFromSymbol(d.CurrencyIn),
+			CurrencyOut: protocol.CurrencyFromSymbol(d.CurrencyOut),
+			Rate:        protocol.AmountFromFloat(d.Rate),
+		}
+	case *api.LimitOrder:
+		declaration = &protocol.LimitOrderDeclaration{
+			Owner:         *protocol.DecodeAddress(d.Owner),
+			BaseCurrency:  protocol.CurrencyFromSymbol(d.BaseCurrency),
+			QuoteCurrency: protocol.CurrencyFromSymbol(d.QuoteCurrency),
+			IsBid:         d.IsBid,
+			Price:         protocol.AmountFromFloat(d.Price),
+			Quantity:      protocol.AmountFromFloat(d.Quantity),
+		}
+	case *api.DelegatedAccount:
+		declaration = &protocol.DelegatedAccountDeclaration{Owner: *protocol.DecodeAddress(d.Owner)}
 	default:
-		panic(0)
+		return nil, fmt.Errorf("apitoprotocol: unknown declaration type %d", transaction.Type)
 	}
 
 	return &protocol.TxDeclaration{
 		Type:        transaction.Type,
 		Declaration: declaration,
-	}
+	}, nil
 }
 
-func ToInput(input *api.TxInput) *protocol.TxInput {
+func ToInput(input *api.TxInput) (*protocol.TxInput, error) {
 	if input == nil {
-		return nil
+		return nil, errors.New("apitoprotocol: missing input")
+	}
+	if err := checkCurrency(input.Currency); err != nil {
+		return nil, err
 	}
 
 	return &protocol.TxInput{
 		Address:  *protocol.DecodeAddress(input.Address),
 		Amount:   protocol.AmountFromFloat(input.Amount),
 		Currency: protocol.CurrencyFromSymbol(input.Currency),
-	}
+	}, nil
 }
 
-func ToOutput(input *api.TxOutput) *protocol.TxOutput {
+func ToOutput(input *api.TxOutput) (*protocol.TxOutput, error) {
 	if input == nil {
-		return nil
+		return nil, errors.New("apitoprotocol: missing output")
+	}
+	if err := checkCurrency(input.Currency); err != nil {
+		return nil, err
 	}
 
 	return &protocol.TxOutput{
 		Address:  *protocol.DecodeAddress(input.Address),
 		Amount:   protocol.AmountFromFloat(input.Amount),
 		Currency: protocol.CurrencyFromSymbol(input.Currency),
+	}, nil
+}
+
+// checkCurrency tells if the symbol has the 3 letters CurrencyFromSymbol reads
+func checkCurrency(symbol string) error {
+	if len(symbol) != 3 || strings.Trim(symbol, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+		return fmt.Errorf("apitoprotocol: invalid currency %q", symbol)
 	}
+	return nil
 }
@@ -0,0 +1,91 @@
+package apitoprotocol
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"republicofminer-client-go/explorer/api"
+	"republicofminer-client-go/protocol/bytestream"
+	"republicofminer-client-go/protocol/converter/protocoltoapi"
+	"testing"
+)
+
+// every declaration type goes through the json api and back to the same serialized transaction
+func TestDeclarations(t *testing.T) {
+	transaction := `{"Hash":"","Expire":1560404881,"Declarations":[
+		{"Type":0,"Signers":["qyl68tygnjx6qqwrsmynmejmc9wxlw7almv3397j","qyaj20aksyvxlfmznyjdqzxrvvf0w7ca7mamwzll"],"Required":1},
+		{"Type":1,"SecretHash":{"Type":1,"Hash":"oR/pKR+lTax0EAntbph8539SDmS0gjPoU+ZHXwmU5b4="}},
+		{"Type":2,"Secret":"NXYBRplyY/bDfjBzVNppa/PzPvIDlOxK3j3urVVh4Jk="},
+		{"Type":3,"Timestamp":1560404881},
+		{"Type":4,"Owner":"qyl68tygnjx6qqwrsmynmejmc9wxlw7almv3397j","CurrencyIn":"IRO","CurrencyOut":"WOD","Rate":1.5},
+		{"Type":5,"Owner":"qyl68tygnjx6qqwrsmynmejmc9wxlw7almv3397j","BaseCurrency":"IRO","QuoteCurrency":"STN","IsBid":true,"Price":0.25,"Quantity":10},
+		{"Type":6,"Owner":"qyl68tygnjx6qqwrsmynmejmc9wxlw7almv3397j"}
+	],"Inputs":[],"Outputs":[]}`
+
+	var decoded api.Transaction
+	if err := json.Unmarshal([]byte(transaction), &decoded); err != nil {
+		t.Fatal("Error decoding the transaction :", err)
+	}
+
+	converted, err := ToTransaction(&decoded)
+	if err != nil {
+		t.Fatal("Error converting the transaction :", err)
+	}
+
+	back, err := protocoltoapi.ToTransaction(converted)
+	if err != nil {
+		t.Fatal("Error converting the transaction back :", err)
+	}
+
+	encoded, err := json.Marshal(back)
+	if err != nil {
+		t.Fatal("Error encoding the transaction :", err)
+	}
+
+	var redecoded api.Transaction
+	if err := json.Unmarshal(encoded, &redecoded); err != nil {
+		t.Fatal("Error decoding the encoded transaction :", err)
+	}
+
+	reconverted, err := ToTransaction(&redecoded)
+	if err != nil {
+		t.Fatal("Error converting the decoded transaction :", err)
+	}
+
+	expected, _ := bytestream.Write(converted)
+	actual, err := bytestream.Write(reconverted)
+	if err != nil || !bytes.Equal(actual, expected) {
+		fmt.Println("expected :", expected, "actual", actual, err)
+		t.Fail()
+	}
+	// the layouts of the accounts are not confirmed, we do not make up a hash
+	if redecoded.Hash != "" {
+		t.Fatal("the hash should be empty, actual", redecoded.Hash)
+	}
+}
+
+func TestUnknownDeclaration(t *testing.T) {
+	var decoded api.Transaction
+	err := json.Unmarshal([]byte(`{"Expire":0,"Declarations":[{"Type":9}]}`), &decoded)
+	if err == nil {
+		t.Fatal("decoding an unknown declaration should fail")
+	}
+}
+
+func TestMissingEntries(t *testing.T) {
+	for _, encoded := range []string{
+		`{"Expire":1,"Inputs":[null]}`,
+		`{"Expire":1,"Outputs":[null]}`,
+		`{"Expire":1,"Declarations":[null]}`,
+		`{"Expire":1,"Fees":{"Address":"","Currency":"","Amount":1}}`,
+		`{"Expire":1,"Inputs":[{"Address":"","Currency":"io","Amount":1}]}`,
+	} {
+		var decoded api.Transaction
+		if err := json.Unmarshal([]byte(encoded), &decoded); err != nil {
+			t.Fatal("Error decoding the transaction :", err)
+		}
+		if _, err := ToTransaction(&decoded); err == nil {
+			t.Fatal(encoded, "should not convert")
+		}
+	}
+}
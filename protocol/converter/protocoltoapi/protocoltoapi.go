@@ -2,14 +2,28 @@
 package protocoltoapi
 
 import (
+	"encoding/base64"
+	"errors"
 	"republicofminer-client-go/explorer/api"
 	"republicofminer-client-go/protocol"
 )
 
-func ToTransaction(transaction *protocol.Transaction) *api.Transaction {
+// ToTransaction leaves the hash empty when the transaction declares an account whose layout is not confirmed
+func ToTransaction(transaction *protocol.Transaction) (*api.Transaction, error) {
+	var encoded string
+	hash, err := transaction.Hash()
+	if err == nil {
+		encoded = hash.ToBase64()
+	} else if !errors.Is(err, protocol.ErrUnconfirmedDeclaration) {
+		return nil, err
+	}
+
 	declarations := make([]*api.TxDeclaration, len(transaction.Declarations))
 	for index, d := range transaction.Declarations {
-		declarations[index] = ToDeclaration(d)
+		declarations[index], err = ToDeclaration(d)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	inputs := make([]*api.TxInput, len(transaction.Inputs))
@@ -23,35 +37,56 @@ func ToTransaction(transaction *protocol.Transaction) *api.Transaction {
 	}
 
 	return &api.Transaction{
-		Hash:         transaction.Hash().ToBase64(),
+		Hash:         encoded,
 		Expire:       &transaction.Expire,
 		Declarations: declarations,
 		Inputs:       inputs,
 		Outputs:      outputs,
 		Message:      string([]byte(transaction.Message)),
 		Fees:         ToInput(transaction.Fees),
-	}
+	}, nil
 }
 
-func ToDeclaration(transaction *protocol.TxDeclaration) *api.TxDeclaration {
+func ToDeclaration(transaction *protocol.TxDeclaration) (*api.TxDeclaration, error) {
 	var declaration interface{}
 
-	switch transaction.Type {
-	case protocol.TxMultiSignature:
-	case protocol.TxHashLock:
-	case protocol.TxSecret:
-		secret := transaction.Declaration.(*protocol.SecretRevelation)
-		declaration = &api.SecretRevelation{Secret: secret.Secret.ToBase64()}
-	case protocol.TxTimeLock:
-	case protocol.TxVendingMachine:
-	case protocol.TxLimitOrder:
-	case protocol.TxDelegatedAccount:
+	switch d := transaction.Declaration.(type) {
+	case *protocol.MultiSignature:
+		signers := make([]string, len(d.Signers))
+		for index, signer := range d.Signers {
+			signers[index] = signer.Encoded
+		}
+		declaration = &api.MultiSignature{Signers: signers, Required: d.Required}
+	case *protocol.HashLockDeclaration:
+		declaration = &api.HashLock{SecretHash: api.SecretHash{Type: d.SecretHash.Type, Hash: base64.StdEncoding.EncodeToString(d.SecretHash.Hash)}}
+	case *protocol.SecretRevelation:
+		declaration = &api.SecretRevelation{Secret: d.Secret.ToBase64()}
+	case *protocol.TimeLockDeclaration:
+		declaration = &api.TimeLock{Timestamp: d.Timestamp}
+	case *protocol.VendingMachineDeclaration:
+		declaration = &api.VendingMachine{
+			Owner:       d.Owner.Encoded,
+			CurrencyIn:  d.CurrencyIn.ToSymbol(),
+			CurrencyOut: d.CurrencyOut.ToSymbol(),
+			Rate:        d.Rate.ToFloat(),
+		}
+	case *protocol.LimitOrderDeclaration:
+		declaration = &api.LimitOrder{
+			Owner:         d.Owner.Encoded,
+			BaseCurrency:  d.BaseCurrency.ToSymbol(),
+			QuoteCurrency: d.QuoteCurrency.ToSymbol(),
+			IsBid:         d.IsBid,
+			Price:         d.Price.ToFloat(),
+			Quantity:      d.Quantity.ToFloat(),
+		}
+	case *protocol.DelegatedAccountDeclaration:
+		declaration = &api.DelegatedAccount{Owner: d.Owner.Encoded}
 	}
 
 	return &api.TxDeclaration{
 		Type:        transaction.Type,
 		Declaration: declaration,
-	}
+	}, nil
 }
 
 func ToInput(input *protocol.TxInput) *api.TxInput {
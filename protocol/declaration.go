@@ -0,0 +1,289 @@
+package protocol
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"republicofminer-client-go/crypto"
+	"republicofminer-client-go/protocol/bytestream"
+)
+
+// ErrMissingDeclaration is returned when serializing a TxDeclaration without its content
+var ErrMissingDeclaration = errors.New("protocol: missing declaration")
+
+// ErrUnconfirmedDeclaration is returned when hashing a transaction with a declaration whose byte layout is not confirmed
+var ErrUnconfirmedDeclaration = errors.New("protocol: the serialization of the declaration is not confirmed with the network")
+
+// confirmed lists the declarations whose byte layout matches the hash of a transaction of the explorer
+// the layout of the others follows the fields of the explorer json, add a vector of a real transaction before confirming one
+var confirmed = map[DeclarationType]bool{TxSecret: true}
+
+// Declaration is the content of a TxDeclaration
+type Declaration interface {
+	bytestream.ByteStreamer
+	bytestream.ByteStreamReader
+}
+
+// NewDeclaration instanciates the empty declaration of the given type
+func NewDeclaration(typ DeclarationType) (Declaration, error) {
+	switch typ {
+	case TxMultiSignature:
+		return &MultiSignature{}, nil
+	case TxHashLock:
+		return &HashLockDeclaration{}, nil
+	case TxSecret:
+		return &SecretRevelation{}, nil
+	case TxTimeLock:
+		return &TimeLockDeclaration{}, nil
+	case TxVendingMachine:
+		return &VendingMachineDeclaration{}, nil
+	case TxLimitOrder:
+		return &LimitOrderDeclaration{}, nil
+	case TxDelegatedAccount:
+		return &DelegatedAccountDeclaration{}, nil
+	}
+	return nil, fmt.Errorf("protocol: unknown declaration type %d", typ)
+}
+
+// MultiSignature is an account that needs the signature of Required signers to spend
+type MultiSignature struct {
+	Signers  []Address
+	Required int32
+}
+
+// HashLockDeclaration is an account that can be spent by revealing the secret of the hash
+type HashLockDeclaration struct {
+	SecretHash SecretHash
+}
+
+// SecretHashType is the algorithm hashing the secret of a HashLock or a mining task
+type SecretHashType byte
+
+const (
+	// SHA3 is the keccak256 of the secret
+	SHA3 SecretHashType = 0
+	// SHA256 is the sha256 of the secret
+	SHA256 SecretHashType = 1
+	// DoubleSHA256 is the sha256 of the sha256 of the secret, like crypto.Hash
+	// it is not in the enum of Caasiope, it only hashes the secrets locally and is never serialized
+	DoubleSHA256 SecretHashType = 2
+)
+
+// ErrUnknownSecretHashType is returned for a SecretHashType we cannot compute
+var ErrUnknownSecretHashType = errors.New("protocol: unknown secret hash type")
+
+// serializable tells if the type has a value in the wire format
+func (typ SecretHashType) serializable() bool {
+	return typ == SHA3 || typ == SHA256
+}
+
+// Hash computes the hash of the secret with the algorithm
+func (typ SecretHashType) Hash(secret Secret) (crypto.Hash256, error) {
+	switch typ {
+	case SHA3:
+		return crypto.Keccak256(secret), nil
+	case SHA256:
+		return crypto.SHA256(secret), nil
+	case DoubleSHA256:
+		return crypto.Hash(secret), nil
+	}
+	return nil, ErrUnknownSecretHashType
+}
+
+type SecretHash struct {
+	Type SecretHashType
+	Hash []byte
+}
+
+// NewSecretHash hashes the secret with the algorithm
+func NewSecretHash(typ SecretHashType, secret Secret) (*SecretHash, error) {
+	hash, err := typ.Hash(secret)
+	if err != nil {
+		return nil, err
+	}
+	return &SecretHash{Type: typ, Hash: hash}, nil
+}
+
+// Matches tells if the secret has the hash
+func (hash *SecretHash) Matches(secret Secret) bool {
+	computed, err := hash.Type.Hash(secret)
+	return err == nil && bytes.Equal(computed, hash.Hash)
+}
+
+// NewHashLock creates the declaration of an account that is spent by revealing the secret
+// the type should have a value in the wire format, the double sha256 has none
+func NewHashLock(typ SecretHashType, secret Secret) (*HashLockDeclaration, error) {
+	if !typ.serializable() {
+		return nil, ErrUnknownSecretHashType
+	}
+	hash, err := NewSecretHash(typ, secret)
+	if err != nil {
+		return nil, err
+	}
+	return &HashLockDeclaration{SecretHash: *hash}, nil
+}
+
+// Unlocks tells if the revealed secret unlocks the hash lock
+func (hashlock *HashLockDeclaration) Unlocks(revelation *SecretRevelation) bool {
+	return hashlock.SecretHash.Matches(revelation.Secret)
+}
+
+// TimeLockDeclaration is an account that cannot be spent before the timestamp
+type TimeLockDeclaration struct {
+	Timestamp int64
+}
+
+// VendingMachineDeclaration is an account that exchanges CurrencyIn for CurrencyOut at the given rate on behalf of the owner
+type VendingMachineDeclaration struct {
+	Owner       Address
+	CurrencyIn  Currency
+	CurrencyOut Currency
+	Rate        Amount
+}
+
+// LimitOrderDeclaration is an account that buys or sells Quantity of BaseCurrency at Price in QuoteCurrency on behalf of the owner
+type LimitOrderDeclaration struct {
+	Owner         Address
+	BaseCurrency  Currency
+	QuoteCurrency Currency
+	IsBid         bool
+	Price         Amount
+	Quantity      Amount
+}
+
+// DelegatedAccountDeclaration is an account spent with the signature of its owner
+type DelegatedAccountDeclaration struct {
+	Owner Address
+}
+
+const SECRET_HASH_SIZE = 32
+
+func (multi *MultiSignature) Write(stream *bytestream.ByteStream) {
+	stream.WriteList(len(multi.Signers), func(i int) bytestream.ByteStreamer { return &multi.Signers[i] })
+	stream.WriteInt32(multi.Required)
+}
+
+func (multi *MultiSignature) Read(stream *bytestream.ByteStream) error {
+	multi.Signers = nil
+	err := stream.ReadList(func(i int) error {
+		var signer Address
+		if err := signer.Read(stream); err != nil {
+			return err
+		}
+		multi.Signers = append(multi.Signers, signer)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	multi.Required, err = stream.ReadInt32()
+	return err
+}
+
+func (hashlock *HashLockDeclaration) Write(stream *bytestream.ByteStream) {
+	hashlock.SecretHash.Write(stream)
+}
+
+func (hashlock *HashLockDeclaration) Read(stream *bytestream.ByteStream) error {
+	return hashlock.SecretHash.Read(stream)
+}
+
+func (hash *SecretHash) Write(stream *bytestream.ByteStream) {
+	if !hash.Type.serializable() {
+		stream.Fail(ErrUnknownSecretHashType)
+		return
+	}
+	if len(hash.Hash) != SECRET_HASH_SIZE {
+		stream.Fail(fmt.Errorf("protocol: invalid secret hash length %d", len(hash.Hash)))
+		return
+	}
+	stream.WriteByte(byte(hash.Type))
+	stream.WriteBytes(hash.Hash)
+}
+
+func (hash *SecretHash) Read(stream *bytestream.ByteStream) error {
+	typ, err := stream.ReadByte()
+	if err != nil {
+		return err
+	}
+	if !SecretHashType(typ).serializable() {
+		return ErrUnknownSecretHashType
+	}
+	hash.Type = SecretHashType(typ)
+	hash.Hash, err = stream.ReadBytes(SECRET_HASH_SIZE)
+	return err
+}
+
+func (timelock *TimeLockDeclaration) Write(stream *bytestream.ByteStream) {
+	stream.WriteInt64(timelock.Timestamp)
+}
+
+func (timelock *TimeLockDeclaration) Read(stream *bytestream.ByteStream) (err error) {
+	timelock.Timestamp, err = stream.ReadInt64()
+	return err
+}
+
+func (machine *VendingMachineDeclaration) Write(stream *bytestream.ByteStream) {
+	machine.Owner.Write(stream)
+	machine.CurrencyIn.Write(stream)
+	machine.CurrencyOut.Write(stream)
+	machine.Rate.Write(stream)
+}
+
+func (machine *VendingMachineDeclaration) Read(stream *bytestream.ByteStream) error {
+	if err := machine.Owner.Read(stream); err != nil {
+		return err
+	}
+	if err := machine.CurrencyIn.Read(stream); err != nil {
+		return err
+	}
+	if err := machine.CurrencyOut.Read(stream); err != nil {
+		return err
+	}
+	return machine.Rate.Read(stream)
+}
+
+func (order *LimitOrderDeclaration) Write(stream *bytestream.ByteStream) {
+	order.Owner.Write(stream)
+	order.BaseCurrency.Write(stream)
+	order.QuoteCurrency.Write(stream)
+	if order.IsBid {
+		stream.WriteByte(1)
+	} else {
+		stream.WriteByte(0)
+	}
+	order.Price.Write(stream)
+	order.Quantity.Write(stream)
+}
+
+func (order *LimitOrderDeclaration) Read(stream *bytestream.ByteStream) error {
+	if err := order.Owner.Read(stream); err != nil {
+		return err
+	}
+	if err := order.BaseCurrency.Read(stream); err != nil {
+		return err
+	}
+	if err := order.QuoteCurrency.Read(stream); err != nil {
+		return err
+	}
+	bid, err := stream.ReadByte()
+	if err != nil {
+		return err
+	}
+	if bid > 1 {
+		return fmt.Errorf("protocol: invalid boolean %d", bid)
+	}
+	order.IsBid = bid == 1
+	if err := order.Price.Read(stream); err != nil {
+		return err
+	}
+	return order.Quantity.Read(stream)
+}
+
+func (delegated *DelegatedAccountDeclaration) Write(stream *bytestream.ByteStream) {
+	delegated.Owner.Write(stream)
+}
+
+func (delegated *DelegatedAccountDeclaration) Read(stream *bytestream.ByteStream) error {
+	return delegated.Owner.Read(stream)
+}
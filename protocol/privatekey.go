@@ -2,6 +2,8 @@ package protocol
 
 import (
 	"encoding/base64"
+	"errors"
+	"fmt"
 	"math/big"
 	"republicofminer-client-go/crypto"
 
@@ -30,11 +32,28 @@ func PrivateKeyFromBase64(encoded string) (*PrivateKey, error) {
 		return nil, err
 	}
 
+	if err := CheckPrivateKey(decoded); err != nil {
+		return nil, err
+	}
 	priv, _ := btcec.PrivKeyFromBytes(btcec.S256(), decoded)
 
 	return &PrivateKey{priv}, nil
 }
 
+// ErrInvalidPrivateKey is returned when the bytes are not a secp256k1 private key
+var ErrInvalidPrivateKey = errors.New("protocol: invalid private key")
+
+// CheckPrivateKey tells if the bytes are a private key, KEY_SIZE bytes of a number between 1 and the order of the curve
+func CheckPrivateKey(bytes []byte) error {
+	if len(bytes) != KEY_SIZE {
+		return fmt.Errorf("%w : %d bytes instead of %d", ErrInvalidPrivateKey, len(bytes), KEY_SIZE)
+	}
+	if scalar := new(big.Int).SetBytes(bytes); scalar.Sign() == 0 || scalar.Cmp(btcec.S256().N) >= 0 {
+		return fmt.Errorf("%w : out of the range of the curve", ErrInvalidPrivateKey)
+	}
+	return nil
+}
+
 func PrivateKeyFromBytes(bytes []byte) *PrivateKey {
 	priv, _ := btcec.PrivKeyFromBytes(btcec.S256(), bytes)
 	return &PrivateKey{priv}
@@ -60,9 +79,13 @@ func (key *PrivateKey) GetPublicKey() *PublicKey {
 	return &PublicKey{key.key.PubKey()}
 }
 
+// SignMessage signs the message with the network prefix, the signature is canonical
 func (key *PrivateKey) SignMessage(message []byte, network []byte) (*Signature, error) {
 	s, err := key.key.Sign(prepare(message, network))
-	return &Signature{s}, err
+	if err != nil {
+		return nil, err
+	}
+	return (&Signature{s}).Canonical(), nil
 }
 
 func PublicKeyFromBase64(encoded string) (*PublicKey, error) {
@@ -71,7 +94,10 @@ func PublicKeyFromBase64(encoded string) (*PublicKey, error) {
 		return nil, err
 	}
 
-	pub, _ := btcec.ParsePubKey(decoded, btcec.S256())
+	pub, err := btcec.ParsePubKey(decoded, btcec.S256())
+	if err != nil {
+		return nil, err
+	}
 
 	return &PublicKey{pub}, nil
 }
@@ -104,29 +130,72 @@ func (key *PublicKey) ToBase64() string {
 	return base64.StdEncoding.EncodeToString(key.ToBytes())
 }
 
+// ErrInvalidSignature is returned when the signature cannot be decoded or its values are out of the curve order
+var ErrInvalidSignature = errors.New("protocol: invalid signature")
+
+// SignatureFromBase64 decodes a signature in one of the encodings
+// the 65 bytes overflow flag, r and s compatible with bouncy castle, the 64 bytes r and s, or DER
+// a high s is accepted, IsCanonical tells if the signature is in its low s form
 func SignatureFromBase64(encoded string) (*Signature, error) {
 	decoded, err := base64.StdEncoding.DecodeString(encoded)
 	if err != nil {
 		return nil, err
 	}
+	return SignatureFromBytes(decoded)
+}
+
+// SignatureFromBytes decodes a signature in one of the encodings accepted by SignatureFromBase64
+func SignatureFromBytes(decoded []byte) (*Signature, error) {
+	var r, s *big.Int
+	switch {
+	case len(decoded) == 65 && decoded[0] <= 0x3:
+		r = FromByteArray(decoded[1:33])
+		s = FromByteArray(decoded[33:])
+	case len(decoded) > 0 && decoded[0] == 0x30:
+		// a DER sequence, unless it is the r and s of 64 bytes that happens to start like one
+		der, err := btcec.ParseDERSignature(decoded, btcec.S256())
+		if err == nil {
+			r, s = der.R, der.S
+			break
+		}
+		if len(decoded) != 64 {
+			return nil, fmt.Errorf("%w : %v", ErrInvalidSignature, err)
+		}
+		fallthrough
+	case len(decoded) == 64:
+		r = FromByteArray(decoded[:32])
+		s = FromByteArray(decoded[32:])
+	default:
+		return nil, fmt.Errorf("%w : unknown encoding of %d bytes", ErrInvalidSignature, len(decoded))
+	}
 
-	left := decoded[1:33]
-	right := decoded[33:]
+	if !crypto.ValidSignatureValues(r, s) {
+		return nil, fmt.Errorf("%w : r or s out of range", ErrInvalidSignature)
+	}
+	return &Signature{&btcec.Signature{R: r, S: s}}, nil
+}
 
-	r := FromByteArray(left)
-	s := FromByteArray(right)
+// IsCanonical tells if s is low, the other value n - s verifies the same signature so only the low one is canonical
+func (sig *Signature) IsCanonical() bool {
+	return crypto.ValidSignatureValues(sig.signature.R, sig.signature.S) && crypto.IsLowS(sig.signature.S)
+}
 
-	return &Signature{&btcec.Signature{R: r, S: s}}, nil
+// Canonical returns the signature in its low s form
+func (sig *Signature) Canonical() *Signature {
+	return &Signature{&btcec.Signature{R: sig.signature.R, S: crypto.NormalizeS(sig.signature.S)}}
 }
 
+// ToBytes encodes the canonical signature in 65 bytes, the overflow flag then r and s compatible with bouncy castle
 func (sig *Signature) ToBytes() []byte {
+	canonical := sig.Canonical().signature
 	overflow := byte(0)
-	left, isOverflow := ToByteArray(sig.signature.R)
+	// the values of a Signature are checked when it is created, they fit in 32 bytes
+	left, isOverflow, _ := ToByteArray(canonical.R)
 	if isOverflow {
 		overflow |= 0x1
 	}
 
-	right, isOverflow := ToByteArray(sig.signature.S)
+	right, isOverflow, _ := ToByteArray(canonical.S)
 	if isOverflow {
 		overflow |= 0x2
 	}
@@ -137,21 +206,21 @@ func (sig *Signature) ToBytes() []byte {
 	return buffer
 }
 
-func ToByteArray(i *big.Int) ([]byte, bool) {
+// ToByteArray encodes the value in 32 bytes, the flag tells if bouncy castle would add a sign byte
+func ToByteArray(i *big.Int) ([]byte, bool, error) {
+	if i.Sign() < 0 {
+		return nil, false, fmt.Errorf("protocol: cannot encode the negative value %v", i)
+	}
 	bytes := i.Bytes()
 	length := len(bytes)
-	if length == 33 {
-		// should not happen because we dont have the sign byte included
-		panic(1)
-		// return bytes[1:], true
-	}
 	if length == 32 {
-		return bytes, overflow(bytes[0])
+		return bytes, overflow(bytes[0]), nil
 	}
 	if length < 32 {
-		return append(make([]byte, 32-length), bytes...), false
+		return append(make([]byte, 32-length), bytes...), false, nil
 	}
-	panic(0)
+	// big.Int does not include the sign byte, the value does not fit in 32 bytes
+	return nil, false, fmt.Errorf("protocol: the value of %d bytes does not fit in 32 bytes", length)
 }
 
 func FromByteArray(bytes []byte) *big.Int {
@@ -3,10 +3,11 @@ package protocol
 import (
 	"encoding/base64"
 	"republicofminer-client-go/crypto"
-	"republicofminer-client-go/protocol/bytestream"
 	"republicofminer-client-go/protocol/format/address32"
 )
 
+// AddressType is the first byte of an address
+// only the ECDSA addresses are derived, the address of a declared account needs explorer vectors we do not have
 type AddressType byte
 
 const (
@@ -49,7 +50,7 @@ const (
 
 type TxDeclaration struct {
 	Type        DeclarationType
-	Declaration bytestream.ByteStreamer
+	Declaration Declaration
 }
 
 type SecretRevelation struct {
@@ -70,6 +71,16 @@ func SecretFromBase64(encoded string) Secret {
 	return Secret(decoded)
 }
 
+// NewSecretRevelation reveals the secret of a SHA3 hash
 func NewSecretRevelation(secret Secret) *SecretRevelation {
 	return &SecretRevelation{Secret: secret, Hash: crypto.Keccak256([]byte(secret))}
 }
+
+// RevealSecret reveals the secret of a hash computed with the given algorithm
+func RevealSecret(typ SecretHashType, secret Secret) (*SecretRevelation, error) {
+	hash, err := typ.Hash(secret)
+	if err != nil {
+		return nil, err
+	}
+	return &SecretRevelation{Secret: secret, Hash: hash}, nil
+}
@@ -1,10 +1,18 @@
 package protocol
 
 import (
+	"bytes"
 	"encoding/base64"
+	"errors"
 	"fmt"
+	"math/big"
+	"math/rand"
 	"republicofminer-client-go/crypto"
+	"republicofminer-client-go/protocol/bytestream"
 	"testing"
+	"testing/quick"
+
+	"github.com/btcsuite/btcd/btcec"
 )
 
 var keys = []string{
@@ -87,8 +95,13 @@ func TestSerialization1(t *testing.T) {
 		Outputs:      []*TxOutput{&TxOutput{Address: *DecodeAddress("qyunuamu8u9axnx8e6y0809qup2599snluyccvd2"), Currency: CurrencyFromSymbol("IRO"), Amount: AmountFromFloat(0.00000001)}},
 	}
 
+	hash, err := transaction.Hash()
+	if err != nil {
+		t.Fatal("Error hashing the transaction :", err)
+	}
+
 	expected := "zIJZB67U0gTUnGq649baM/5ylbUE1ydm5WpJ7xn2XfQ="
-	actual := transaction.Hash().ToBase64()
+	actual := hash.ToBase64()
 	if actual != expected {
 		fmt.Println("expected :", expected, "actual", actual)
 		t.Fail()
@@ -105,8 +118,13 @@ func TestSerialization2(t *testing.T) {
 		Outputs:      []*TxOutput{&TxOutput{Address: *DecodeAddress("qy2t4fvr6q5k0235p5xg5wu64tn883ks20cg424c"), Currency: CurrencyFromSymbol("WOD"), Amount: AmountFromFloat(0.00000001)}},
 	}
 
+	hash, err := transaction.Hash()
+	if err != nil {
+		t.Fatal("Error hashing the transaction :", err)
+	}
+
 	expected := "KAapdGf1unoM8dSsN+SHkqsQKXDP2Y962RnkanRFYcg="
-	actual := transaction.Hash().ToBase64()
+	actual := hash.ToBase64()
 	if actual != expected {
 		fmt.Println("expected :", expected, "actual", actual)
 		t.Fail()
@@ -197,3 +215,343 @@ func TestAddress(t *testing.T) {
 		}
 	}
 }
+
+func sample() *Transaction {
+	secret, _ := base64.StdEncoding.DecodeString("NXYBRplyY/bDfjBzVNppa/PzPvIDlOxK3j3urVVh4Jk=")
+	return &Transaction{
+		Expire:       1560404881,
+		Fees:         &TxInput{Address: *DecodeAddress("qyl68tygnjx6qqwrsmynmejmc9wxlw7almv3397j"), Currency: CurrencyFromSymbol("IRO"), Amount: AmountFromFloat(0.001)},
+		Declarations: []*TxDeclaration{&TxDeclaration{Type: TxSecret, Declaration: NewSecretRevelation(Secret(secret))}},
+		Inputs:       []*TxInput{&TxInput{Address: *DecodeAddress("qgefrlzgsx998sj9lvj4hw39plh22llxwlj4tuvp"), Currency: CurrencyFromSymbol("WOD"), Amount: AmountFromFloat(0.00000001)}},
+		Outputs:      []*TxOutput{&TxOutput{Address: *DecodeAddress("qy2t4fvr6q5k0235p5xg5wu64tn883ks20cg424c"), Currency: CurrencyFromSymbol("WOD"), Amount: AmountFromFloat(0.00000001)}},
+		Message:      TransactionMessage("a message"),
+	}
+}
+
+func TestReadTransaction(t *testing.T) {
+	transactions := []*Transaction{sample(), &Transaction{Expire: -1}}
+
+	for _, transaction := range transactions {
+		roundtrip(t, transaction)
+	}
+}
+
+// roundtrip checks that the transaction read from its serialization is serialized to the same bytes
+func roundtrip(t *testing.T, transaction *Transaction) {
+	serialized, err := bytestream.Write(transaction)
+	if err != nil {
+		t.Fatal("Error writing the transaction :", err)
+	}
+
+	read, err := TransactionFromBytes(serialized)
+	if err != nil {
+		t.Fatal("Error reading the transaction :", err)
+	}
+
+	written, err := bytestream.Write(read)
+	if err != nil {
+		t.Fatal("Error writing the read transaction :", err)
+	}
+	if !bytes.Equal(written, serialized) {
+		fmt.Println("expected :", serialized, "actual", written)
+		t.Fail()
+	}
+}
+
+func TestReadTruncatedTransaction(t *testing.T) {
+	serialized, _ := bytestream.Write(sample())
+	// the message takes the rest of the stream so we cut before it
+	for length := 0; length < len(serialized)-len("a message")-1; length++ {
+		_, err := TransactionFromBytes(serialized[:length])
+		if err == nil {
+			t.Fatal("Reading a truncated transaction should fail, length", length)
+		}
+	}
+}
+
+func FuzzReadTransaction(f *testing.F) {
+	for _, transaction := range []*Transaction{sample(), &Transaction{}} {
+		serialized, _ := bytestream.Write(transaction)
+		f.Add(serialized)
+	}
+	f.Fuzz(func(t *testing.T, serialized []byte) {
+		transaction, err := TransactionFromBytes(serialized)
+		if err != nil {
+			return
+		}
+
+		// what we parse is serialized back to the same bytes and hash
+		written, err := bytestream.Write(transaction)
+		if err != nil || !bytes.Equal(written, serialized) {
+			t.Fatal("the transaction is not serialized back to the same bytes", err)
+		}
+		hash, err := transaction.Hash()
+		if err == nil && !bytes.Equal(hash, crypto.Keccak256(serialized)) {
+			t.Fatal("the hash of the transaction does not match")
+		}
+	})
+}
+
+// declaration generates a valid declaration of the given type
+func declaration(r *rand.Rand, typ DeclarationType, address func() Address) Declaration {
+	bytes := func(size int) []byte {
+		b := make([]byte, size)
+		r.Read(b)
+		return b
+	}
+
+	switch typ {
+	case TxMultiSignature:
+		signers := make([]Address, 1+r.Intn(5))
+		for index := range signers {
+			signers[index] = address()
+		}
+		return &MultiSignature{Signers: signers, Required: int32(1 + r.Intn(len(signers)))}
+	case TxHashLock:
+		return &HashLockDeclaration{SecretHash: SecretHash{Type: SecretHashType(r.Intn(int(SHA256) + 1)), Hash: bytes(SECRET_HASH_SIZE)}}
+	case TxSecret:
+		return NewSecretRevelation(bytes(SECRET_SIZE))
+	case TxTimeLock:
+		return &TimeLockDeclaration{Timestamp: r.Int63()}
+	case TxVendingMachine:
+		return &VendingMachineDeclaration{Owner: address(), CurrencyIn: Currency(r.Intn(17576)), CurrencyOut: Currency(r.Intn(17576)), Rate: Amount(r.Int63())}
+	case TxLimitOrder:
+		return &LimitOrderDeclaration{Owner: address(), BaseCurrency: Currency(r.Intn(17576)), QuoteCurrency: Currency(r.Intn(17576)), IsBid: r.Intn(2) == 0, Price: Amount(r.Int63()), Quantity: Amount(r.Int63())}
+	case TxDelegatedAccount:
+		return &DelegatedAccountDeclaration{Owner: address()}
+	}
+	panic(typ)
+}
+
+// random generates a valid transaction with up to the given number of inputs, outputs and message length
+func random(r *rand.Rand, elements int, message int) *Transaction {
+	address := func() Address {
+		hash := make([]byte, 20)
+		r.Read(hash)
+		return *CreateAddress(AddressType(1+r.Intn(int(DelegatedAccount))), hash)
+	}
+	io := func() TxInputOutput {
+		return TxInputOutput{Address: address(), Currency: Currency(r.Intn(17576)), Amount: Amount(r.Int63())}
+	}
+
+	transaction := &Transaction{Expire: r.Int63()}
+	if r.Intn(2) == 0 {
+		fees := TxInput(io())
+		transaction.Fees = &fees
+	}
+	for i := r.Intn(elements + 1); i > 0; i-- {
+		typ := DeclarationType(r.Intn(int(TxDelegatedAccount) + 1))
+		transaction.Declarations = append(transaction.Declarations, &TxDeclaration{Type: typ, Declaration: declaration(r, typ, address)})
+	}
+	for i := r.Intn(elements + 1); i > 0; i-- {
+		input := TxInput(io())
+		transaction.Inputs = append(transaction.Inputs, &input)
+	}
+	for i := r.Intn(elements + 1); i > 0; i-- {
+		output := TxOutput(io())
+		transaction.Outputs = append(transaction.Outputs, &output)
+	}
+	if r.Intn(2) == 0 {
+		transaction.Message = make(TransactionMessage, r.Intn(message+1))
+		r.Read(transaction.Message)
+	}
+	return transaction
+}
+
+func TestLargeTransactions(t *testing.T) {
+	property := func(seed int64) bool {
+		transaction := random(rand.New(rand.NewSource(seed)), bytestream.MaxListLength, 100000)
+
+		first, err := bytestream.Write(transaction)
+		if err != nil {
+			t.Log("Error writing the transaction :", err)
+			return false
+		}
+		// the serialization is stable
+		second, _ := bytestream.Write(transaction)
+		if !bytes.Equal(first, second) {
+			return false
+		}
+
+		roundtrip(t, transaction)
+		return !t.Failed()
+	}
+
+	if err := quick.Check(property, &quick.Config{MaxCount: 50}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTooManyInputs(t *testing.T) {
+	transaction := random(rand.New(rand.NewSource(1)), 0, 0)
+	for i := 0; i <= bytestream.MaxListLength; i++ {
+		transaction.Inputs = append(transaction.Inputs, &TxInput{Address: *DecodeAddress("qyl68tygnjx6qqwrsmynmejmc9wxlw7almv3397j")})
+	}
+
+	_, err := transaction.Hash()
+	if !errors.Is(err, bytestream.ErrListTooLong) {
+		t.Fatal("expected :", bytestream.ErrListTooLong, "actual", err)
+	}
+}
+
+func TestDeclarations(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	address := func() Address { return *DecodeAddress("qyl68tygnjx6qqwrsmynmejmc9wxlw7almv3397j") }
+
+	for typ := TxMultiSignature; typ <= TxDelegatedAccount; typ++ {
+		transaction := &Transaction{Declarations: []*TxDeclaration{&TxDeclaration{Type: typ, Declaration: declaration(r, typ, address)}}}
+		roundtrip(t, transaction)
+
+		read, _ := TransactionFromBytes(mustWrite(t, transaction))
+		if read.Declarations[0].Type != typ || fmt.Sprint(read.Declarations[0].Declaration) != fmt.Sprint(transaction.Declarations[0].Declaration) {
+			fmt.Println("expected :", transaction.Declarations[0].Declaration, "actual", read.Declarations[0].Declaration)
+			t.Fail()
+		}
+	}
+}
+
+func TestUnconfirmedDeclarations(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	address := func() Address { return *DecodeAddress("qyl68tygnjx6qqwrsmynmejmc9wxlw7almv3397j") }
+
+	for typ := TxMultiSignature; typ <= TxDelegatedAccount; typ++ {
+		transaction := &Transaction{Declarations: []*TxDeclaration{&TxDeclaration{Type: typ, Declaration: declaration(r, typ, address)}}}
+		_, err := transaction.Hash()
+		if typ == TxSecret && err != nil {
+			t.Fatal("the secret revelation has vectors of the network :", err)
+		}
+		if typ != TxSecret && !errors.Is(err, ErrUnconfirmedDeclaration) {
+			t.Fatal("type", typ, "expected :", ErrUnconfirmedDeclaration, "actual", err)
+		}
+	}
+}
+
+func TestMissingDeclaration(t *testing.T) {
+	transaction := &Transaction{Declarations: []*TxDeclaration{&TxDeclaration{Type: TxTimeLock}}}
+	if _, err := transaction.Hash(); err != ErrMissingDeclaration {
+		t.Fatal("expected :", ErrMissingDeclaration, "actual", err)
+	}
+}
+
+func mustWrite(t *testing.T, data bytestream.ByteStreamer) []byte {
+	serialized, err := bytestream.Write(data)
+	if err != nil {
+		t.Fatal("Error writing :", err)
+	}
+	return serialized
+}
+
+func TestSecretHashTypes(t *testing.T) {
+	secret := SecretFromBase64("NXYBRplyY/bDfjBzVNppa/PzPvIDlOxK3j3urVVh4Jk=")
+	expected := map[SecretHashType]crypto.Hash256{
+		SHA3:         crypto.Keccak256(secret),
+		SHA256:       crypto.SHA256(secret),
+		DoubleSHA256: crypto.SHA256(crypto.SHA256(secret)),
+	}
+
+	for typ, hash := range expected {
+		hashlock, err := NewHashLock(typ, secret)
+		if typ == DoubleSHA256 {
+			// the double sha256 only hashes the secrets of the mining tasks, a hash lock could not be written
+			if err != ErrUnknownSecretHashType {
+				t.Fatal("expected :", ErrUnknownSecretHashType, "actual", err)
+			}
+			hashlock = &HashLockDeclaration{SecretHash: SecretHash{Type: typ, Hash: hash}}
+		} else if err != nil {
+			t.Fatal("Error creating the hash lock :", err)
+		}
+		if !bytes.Equal(hashlock.SecretHash.Hash, hash) {
+			t.Fatal("type", typ, "expected :", hash.ToBase64(), "actual", base64.StdEncoding.EncodeToString(hashlock.SecretHash.Hash))
+		}
+
+		revelation, _ := RevealSecret(typ, secret)
+		if !bytes.Equal(revelation.Hash, hash) || !hashlock.Unlocks(revelation) {
+			t.Fatal("the secret should unlock the hash lock of type", typ)
+		}
+		if hashlock.Unlocks(NewSecretRevelation(make(Secret, SECRET_SIZE))) {
+			t.Fatal("another secret should not unlock the hash lock of type", typ)
+		}
+	}
+
+	// the algorithms are not interchangeable
+	hashlock, _ := NewHashLock(SHA256, secret)
+	hashlock.SecretHash.Type = DoubleSHA256
+	if hashlock.Unlocks(NewSecretRevelation(secret)) {
+		t.Fatal("the secret hash type should be part of the lock")
+	}
+
+	if _, err := NewHashLock(DoubleSHA256+1, secret); err != ErrUnknownSecretHashType {
+		t.Fatal("expected :", ErrUnknownSecretHashType, "actual", err)
+	}
+	// the double sha256 has no confirmed value in the wire format
+	for _, typ := range []SecretHashType{DoubleSHA256, DoubleSHA256 + 1} {
+		unknown := &HashLockDeclaration{SecretHash: SecretHash{Type: typ, Hash: make([]byte, SECRET_HASH_SIZE)}}
+		if _, err := bytestream.Write(unknown); err != ErrUnknownSecretHashType {
+			t.Fatal("type", typ, "expected :", ErrUnknownSecretHashType, "actual", err)
+		}
+	}
+	if err := bytestream.Read(append([]byte{byte(DoubleSHA256)}, make([]byte, SECRET_HASH_SIZE)...), &SecretHash{}); err != ErrUnknownSecretHashType {
+		t.Fatal("expected :", ErrUnknownSecretHashType, "actual", err)
+	}
+}
+
+func TestAmountFromFloat(t *testing.T) {
+	for _, amount := range []Amount{1, 10000000, 189999999, 190000000, 123456789012} {
+		if actual := AmountFromFloat(amount.ToFloat()); actual != amount {
+			t.Fatal("expected :", amount, "actual", actual)
+		}
+	}
+}
+
+func TestCanonicalSignature(t *testing.T) {
+	for _, encoded := range signatures {
+		signature, err := SignatureFromBase64(encoded)
+		if err != nil {
+			t.Fatal("Error decoding the signature :", err)
+		}
+		if !signature.IsCanonical() {
+			t.Fatal("the signature should have a low s :", encoded)
+		}
+
+		// n - s verifies the same message, it is parsed but not canonical
+		high := &Signature{&btcec.Signature{R: signature.signature.R, S: new(big.Int).Sub(btcec.S256().N, signature.signature.S)}}
+		if high.IsCanonical() {
+			t.Fatal("the signature should have a high s :", encoded)
+		}
+		if actual := high.ToBase64(); actual != encoded {
+			t.Fatal("expected :", encoded, "actual", actual)
+		}
+
+		// the encodings decode to the same signature
+		decoded, _ := base64.StdEncoding.DecodeString(encoded)
+		for _, other := range [][]byte{decoded[1:], signature.signature.Serialize()} {
+			parsed, err := SignatureFromBytes(other)
+			if err != nil {
+				t.Fatal("Error decoding the signature :", err)
+			}
+			if actual := parsed.ToBase64(); actual != encoded {
+				t.Fatal("expected :", encoded, "actual", actual)
+			}
+		}
+	}
+}
+
+func TestInvalidSignatures(t *testing.T) {
+	valid, _ := base64.StdEncoding.DecodeString(signatures[0])
+	zero := append([]byte{0}, make([]byte, 64)...)
+	order := append(append([]byte{0}, btcec.S256().N.Bytes()...), valid[33:]...)
+	flag := append([]byte{4}, valid[1:]...)
+	der := append([]byte{0x30}, make([]byte, 10)...)
+	for _, invalid := range [][]byte{nil, valid[:64][1:], zero, order, flag, der} {
+		if _, err := SignatureFromBytes(invalid); !errors.Is(err, ErrInvalidSignature) {
+			t.Fatal("expected :", ErrInvalidSignature, "actual", err, "for", invalid)
+		}
+	}
+
+	if _, _, err := ToByteArray(new(big.Int).Lsh(big.NewInt(1), 256)); err == nil {
+		t.Fatal("a value of 33 bytes should not be encoded")
+	}
+	if bytes, overflow, err := ToByteArray(big.NewInt(0x80)); err != nil || overflow || len(bytes) != 32 {
+		t.Fatal("expected : 32 bytes without overflow, actual", bytes, overflow, err)
+	}
+}
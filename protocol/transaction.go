@@ -1,10 +1,17 @@
 package protocol
 
 import (
+	"errors"
+	"fmt"
+	"math"
 	"republicofminer-client-go/crypto"
 	"republicofminer-client-go/protocol/bytestream"
+	"republicofminer-client-go/protocol/format/address32"
 )
 
+// ErrUnknownAddressType is returned when reading an address with a type we do not know
+var ErrUnknownAddressType = errors.New("protocol: unknown address type")
+
 type Transaction struct {
 	Expire       int64
 	Fees         *TxInput
@@ -31,8 +38,9 @@ func (amount Amount) ToFloat() float64 {
 	return float64(amount) / 100000000
 }
 
+// AmountFromFloat rounds to the closest amount, the float of an amount is not always exact
 func AmountFromFloat(float float64) Amount {
-	return Amount(int64(float * 100000000))
+	return Amount(int64(math.Round(float * 100000000)))
 }
 
 type Currency int16
@@ -105,6 +113,10 @@ func (io *TxOutput) This() *TxInputOutput {
 }
 
 func (declaration *TxDeclaration) Write(stream *bytestream.ByteStream) {
+	if declaration.Declaration == nil {
+		stream.Fail(ErrMissingDeclaration)
+		return
+	}
 	stream.WriteByte(byte(declaration.Type))
 	declaration.Declaration.Write(stream)
 }
@@ -117,6 +129,159 @@ func (secret TransactionMessage) Write(stream *bytestream.ByteStream) {
 	stream.WriteBytes([]byte(secret))
 }
 
-func (transaction *Transaction) Hash() crypto.Hash256 {
-	return crypto.Keccak256(bytestream.Write(transaction))
+func (transaction *Transaction) Read(stream *bytestream.ByteStream) error {
+	expire, err := stream.ReadInt64()
+	if err != nil {
+		return err
+	}
+	transaction.Expire = expire
+
+	transaction.Fees = nil
+	_, err = stream.ReadNullable(func() error {
+		transaction.Fees = &TxInput{}
+		return transaction.Fees.Read(stream)
+	})
+	if err != nil {
+		return err
+	}
+
+	transaction.Declarations = nil
+	err = stream.ReadList(func(i int) error {
+		declaration := &TxDeclaration{}
+		transaction.Declarations = append(transaction.Declarations, declaration)
+		return declaration.Read(stream)
+	})
+	if err != nil {
+		return err
+	}
+
+	transaction.Inputs = nil
+	err = stream.ReadList(func(i int) error {
+		input := &TxInput{}
+		transaction.Inputs = append(transaction.Inputs, input)
+		return input.Read(stream)
+	})
+	if err != nil {
+		return err
+	}
+
+	transaction.Outputs = nil
+	err = stream.ReadList(func(i int) error {
+		output := &TxOutput{}
+		transaction.Outputs = append(transaction.Outputs, output)
+		return output.Read(stream)
+	})
+	if err != nil {
+		return err
+	}
+
+	transaction.Message = nil
+	_, err = stream.ReadNullable(func() error {
+		return transaction.Message.Read(stream)
+	})
+	return err
+}
+
+func (io *TxInputOutput) Read(stream *bytestream.ByteStream) error {
+	if err := io.Address.Read(stream); err != nil {
+		return err
+	}
+	if err := io.Currency.Read(stream); err != nil {
+		return err
+	}
+	return io.Amount.Read(stream)
+}
+
+func (io *TxInput) Read(stream *bytestream.ByteStream) error {
+	return (*TxInputOutput)(io).Read(stream)
+}
+
+func (io *TxOutput) Read(stream *bytestream.ByteStream) error {
+	return (*TxInputOutput)(io).Read(stream)
+}
+
+func (address *Address) Read(stream *bytestream.ByteStream) error {
+	typ, err := stream.ReadByte()
+	if err != nil {
+		return err
+	}
+	if typ < byte(ECDSA) || typ > byte(DelegatedAccount) {
+		return ErrUnknownAddressType
+	}
+	hash, err := stream.ReadBytes(address32.RAW_SIZE - 1)
+	if err != nil {
+		return err
+	}
+	*address = *CreateAddress(AddressType(typ), hash)
+	return nil
+}
+
+func (currency *Currency) Read(stream *bytestream.ByteStream) error {
+	i, err := stream.ReadInt16()
+	*currency = Currency(i)
+	return err
+}
+
+func (amount *Amount) Read(stream *bytestream.ByteStream) error {
+	i, err := stream.ReadInt64()
+	*amount = Amount(i)
+	return err
+}
+
+func (declaration *TxDeclaration) Read(stream *bytestream.ByteStream) error {
+	typ, err := stream.ReadByte()
+	if err != nil {
+		return err
+	}
+	declaration.Type = DeclarationType(typ)
+
+	content, err := NewDeclaration(declaration.Type)
+	if err != nil {
+		return err
+	}
+	if err := content.Read(stream); err != nil {
+		return err
+	}
+	declaration.Declaration = content
+	return nil
+}
+
+func (secret *SecretRevelation) Read(stream *bytestream.ByteStream) error {
+	bytes, err := stream.ReadBytes(SECRET_SIZE)
+	if err != nil {
+		return err
+	}
+	*secret = *NewSecretRevelation(Secret(bytes))
+	return nil
+}
+
+// the message is the last field of the transaction so it takes the rest of the stream
+func (message *TransactionMessage) Read(stream *bytestream.ByteStream) error {
+	bytes, err := stream.ReadBytes(stream.Remaining())
+	*message = TransactionMessage(bytes)
+	return err
+}
+
+// TransactionFromBytes parses the transaction serialized by Write
+func TransactionFromBytes(bytes []byte) (*Transaction, error) {
+	transaction := &Transaction{}
+	if err := bytestream.Read(bytes, transaction); err != nil {
+		return nil, err
+	}
+	return transaction, nil
+}
+
+// Hash fails when the transaction cannot be serialized or declares an account whose layout is not confirmed
+func (transaction *Transaction) Hash() (crypto.Hash256, error) {
+	serialized, err := bytestream.Write(transaction)
+	if err != nil {
+		return nil, err
+	}
+	// a hash we are not sure of would be signed and rejected by the network
+	for _, declaration := range transaction.Declarations {
+		if !confirmed[declaration.Type] {
+			return nil, fmt.Errorf("%w : type %d", ErrUnconfirmedDeclaration, declaration.Type)
+		}
+	}
+	return crypto.Keccak256(serialized), nil
 }
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"republicofminer-client-go/explorer/api"
+	"republicofminer-client-go/protocol/format/address32"
+	"strconv"
+)
+
+// txCommand prints the transaction of the hash
+func txCommand(options *options, args []string) int {
+	flags := newFlags("tx", "hash")
+	if code, ok := parse(flags, args, 1, 1); !ok {
+		return code
+	}
+	explorer := options.connectExplorer()
+	defer explorer.Close()
+
+	transaction, err := explorer.GetTransaction(flags.Arg(0))
+	if err != nil {
+		return failed("Error getting the transaction", err)
+	}
+	return printJSON(transaction)
+}
+
+// blockCommand prints the block of the height or the hash
+func blockCommand(options *options, args []string) int {
+	flags := newFlags("block", "height|hash")
+	if code, ok := parse(flags, args, 1, 1); !ok {
+		return code
+	}
+	explorer := options.connectExplorer()
+	defer explorer.Close()
+
+	var ledger *api.Ledger
+	height, err := strconv.ParseInt(flags.Arg(0), 10, 64)
+	if err == nil {
+		ledger, err = explorer.GetLedgerByHeight(height)
+	} else {
+		ledger, err = explorer.GetLedgerByHash(flags.Arg(0))
+	}
+	if err != nil {
+		return failed("Error getting the block", err)
+	}
+	return printJSON(ledger)
+}
+
+// accountCommand prints the balance of the address
+func accountCommand(options *options, args []string) int {
+	flags := newFlags("account", "address")
+	if code, ok := parse(flags, args, 1, 1); !ok {
+		return code
+	}
+	if _, _, err := address32.Decode(flags.Arg(0)); err != nil {
+		return failed("Invalid address", err)
+	}
+	explorer := options.connectExplorer()
+	defer explorer.Close()
+
+	account, err := explorer.GetAccount(flags.Arg(0))
+	if err != nil {
+		return failed("Error getting the account", err)
+	}
+	return printJSON(account)
+}
+
+// printJSON prints the value as indented json
+func printJSON(value interface{}) int {
+	encoded, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return failed("Error encoding the answer", err)
+	}
+	fmt.Println(string(encoded))
+	return exitOK
+}
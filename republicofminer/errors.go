@@ -0,0 +1,38 @@
+package republicofminer
+
+import (
+	"errors"
+	"fmt"
+	"republicofminer-client-go/common/websocket"
+)
+
+var (
+	// ErrNoTask is returned when the game server has no mining task to give
+	ErrNoTask = errors.New("republicofminer: no mining task available")
+	// ErrTimeout is returned when the game server does not answer in time
+	ErrTimeout = websocket.ErrTimeout
+	// ErrUnexpectedResponse is returned when the game server answers with an unknown type or data that cannot be read
+	ErrUnexpectedResponse = websocket.ErrUnexpectedResponse
+	// ErrRefused is returned when the game server does not claim the mining reward, the error may be a *RefusedError
+	ErrRefused = errors.New("republicofminer: claim refused")
+)
+
+// ServerError is returned when the game server answers with a non zero result code
+type ServerError = websocket.ResultError
+
+// RefusedError is returned when the game server answers a ClaimMiningRequest with a non zero result code
+type RefusedError struct {
+	*ServerError
+}
+
+func (err *RefusedError) Error() string {
+	return fmt.Sprintf("republicofminer: claim refused with result code %d", err.ResultCode)
+}
+
+func (err *RefusedError) Unwrap() error {
+	return err.ServerError
+}
+
+func (err *RefusedError) Is(target error) bool {
+	return target == ErrRefused
+}
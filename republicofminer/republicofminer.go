@@ -1,20 +1,113 @@
 package republicofminer
 
 import (
+	"context"
+	"log"
 	"republicofminer-client-go/common/websocket"
 	"republicofminer-client-go/republicofminer/api"
+	"time"
+
+	json "republicofminer-client-go/common/json"
 )
 
-var client *websocket.WebSocketClient
+// DefaultEndpoint is the game server of the main network
+const DefaultEndpoint = "game.republicofminer.com:2026"
+
+// DefaultTimeout is the maximum duration we wait for the game server to answer a request
+const DefaultTimeout = 30 * time.Second
+
+// Options configures the connection to a game server, the zero value connects to DefaultEndpoint
+type Options struct {
+	// Endpoint is the host and port of the game server
+	Endpoint string
+	// Secure connects with TLS (wss)
+	Secure bool
+	// Dialer opens the connection, its TLSClientConfig is used when Secure
+	Dialer *websocket.Dialer
+	// Logger receives the logs of the connection, the standard logger when nil
+	Logger *log.Logger
+	// Frames receives the raw messages of the connection, they are not logged when nil
+	Frames *log.Logger
+	// Timeout is the maximum duration we wait for an answer, DefaultTimeout when zero
+	Timeout time.Duration
+}
+
+// GameServer is a client of the republic of miner game server
+type GameServer struct {
+	client  *websocket.WebSocketClient
+	options Options
+}
+
+// New instanciates a game server client, the requests sent before Connect wait for the connection
+func New(options Options) *GameServer {
+	if options.Endpoint == "" {
+		options.Endpoint = DefaultEndpoint
+	}
+	if options.Timeout == 0 {
+		options.Timeout = DefaultTimeout
+	}
+
+	client := websocket.Client(api.CreateResponse)
+	client.Dialer = options.Dialer
+	client.Logger = options.Logger
+	client.Frames = options.Frames
 
-func Connect() {
-	client = websocket.Client(api.CreateResponse)
-	client.Connect("game.republicofminer.com:2026")
+	return &GameServer{client: client, options: options}
 }
 
-func GetMiningTask(address string, resource string) *api.MiningTask {
+// Connect to the game server and blocks the thread until Close is called
+func (server *GameServer) Connect() {
+	scheme := "ws"
+	if server.options.Secure {
+		scheme = "wss"
+	}
+	server.client.Connect(scheme + "://" + server.options.Endpoint)
+}
+
+// Close the connection to the game server
+func (server *GameServer) Close() {
+	server.client.Close()
+}
+
+func (server *GameServer) do(request json.Request, t string) (json.Response, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), server.options.Timeout)
+	defer cancel()
+	return server.client.Do(ctx, request, t)
+}
+
+func (server *GameServer) GetMiningTask(address string, resource string) (*api.MiningTask, error) {
 	request := api.GetMiningTaskRequest{Address: address, Resource: resource}
-	response := <-client.Request(client.RequestMessage(&request, "GetMiningTaskRequest"))
-	data := response.Data.(*api.GetMiningTaskResponse)
-	return data.Task
+	response, err := server.do(&request, "GetMiningTaskRequest")
+	if err != nil {
+		return nil, err
+	}
+	data, ok := response.(*api.GetMiningTaskResponse)
+	if !ok {
+		return nil, ErrUnexpectedResponse
+	}
+	if data.Task == nil {
+		return nil, ErrNoTask
+	}
+	return data.Task, nil
+}
+
+// ClaimMining asks the game server to claim the reward of the task for the receiver with the secret in base 64
+// it returns the hash of the claim transaction sent by the server
+func (server *GameServer) ClaimMining(taskAddress string, secret string, receiver string) (string, error) {
+	request := api.ClaimMiningRequest{TaskAddress: taskAddress, Secret: secret, Receiver: receiver}
+	response, err := server.do(&request, "ClaimMiningRequest")
+	if result, ok := err.(*ServerError); ok {
+		return "", &RefusedError{result}
+	}
+	if err != nil {
+		return "", err
+	}
+	data, ok := response.(*api.ClaimMiningResponse)
+	if !ok {
+		return "", ErrUnexpectedResponse
+	}
+	if data.TransactionHash == "" {
+		return "", ErrRefused
+	}
+	return data.TransactionHash, nil
 }
@@ -0,0 +1,61 @@
+package republicofminer
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"republicofminer-client-go/common/websocket/websockettest"
+	"testing"
+	"time"
+)
+
+// standin answers the claims with the given transaction hash and result code
+func standin(t *testing.T, hash string, result byte) *httptest.Server {
+	return websockettest.Server(t, map[string]websockettest.Responder{
+		"ClaimMiningRequest": func(data json.RawMessage) []websockettest.Message {
+			var request struct{ TaskAddress, Secret, Receiver string }
+			json.Unmarshal(data, &request)
+			if request.TaskAddress == "" || request.Secret == "" || request.Receiver == "" {
+				t.Error("unexpected request :", string(data))
+			}
+			return websockettest.Response("ClaimMiningResponse", map[string]string{"TransactionHash": hash}, result)
+		},
+	})
+}
+
+func connect(server *httptest.Server) *GameServer {
+	game := New(Options{Endpoint: websockettest.Address(server), Timeout: time.Second})
+	go game.Connect()
+	return game
+}
+
+func TestClaimMining(t *testing.T) {
+	server := standin(t, "hash", 0)
+	defer server.Close()
+
+	game := connect(server)
+	defer game.Close()
+
+	hash, err := game.ClaimMining("task", "secret", "receiver")
+	if err != nil || hash != "hash" {
+		t.Fatal("expected : hash actual", hash, err)
+	}
+}
+
+func TestClaimMiningRefused(t *testing.T) {
+	server := standin(t, "", 2)
+	defer server.Close()
+
+	game := connect(server)
+	defer game.Close()
+
+	_, err := game.ClaimMining("task", "secret", "receiver")
+	if !errors.Is(err, ErrRefused) {
+		t.Fatal("expected :", ErrRefused, "actual", err)
+	}
+
+	var result *ServerError
+	if !errors.As(err, &result) || result.ResultCode != 2 {
+		t.Fatal("expected the result code 2, actual", err)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"republicofminer-client-go/explorer"
+	"republicofminer-client-go/miner"
+	"republicofminer-client-go/wallet"
+	"republicofminer-client-go/web"
+	"strconv"
+	"strings"
+)
+
+// mining holds the options of the miner shared by serve and mine
+type mining struct {
+	account       *string
+	workers       *int
+	strategy      *string
+	claim         *string
+	confirmations *int64
+	weights       *string
+	goals         *string
+}
+
+func miningFlags(flags *flag.FlagSet) *mining {
+	return &mining{
+		account:       flags.String("account", "", "account of the wallet receiving the rewards, the default account when empty"),
+		workers:       flags.Int("workers", 0, "goroutines searching the secrets, one per core when zero"),
+		strategy:      flags.String("strategy", miner.WeightedRandomStrategy, "resource choice : "+strings.Join([]string{miner.WeightedRandomStrategy, miner.RoundRobinStrategy, miner.InventoryStrategy, miner.ValueStrategy}, ", ")),
+		claim:         flags.String("claim", miner.ClaimThroughExplorer, "claim the rewards through the explorer or the game server : explorer or server"),
+		confirmations: flags.Int64("confirmations", miner.DefaultConfirmations, "ledgers after the inclusion of a claim before it is confirmed"),
+		weights:       flags.String("weights", "", "weights of the resources for the random strategy, for example WOD=2,STN=1"),
+		goals:         flags.String("goals", "", "balances the inventory strategy tries to reach, for example WOD=100,IRO=50"),
+	}
+}
+
+// resources parses the values of the resources like WOD=2,STN=1
+func resources(encoded string) (map[string]float64, error) {
+	if encoded == "" {
+		return nil, nil
+	}
+	values := map[string]float64{}
+	for _, pair := range strings.Split(encoded, ",") {
+		resource, value, ok := strings.Cut(pair, "=")
+		parsed, err := strconv.ParseFloat(value, 64)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("invalid resource value %q, expected RESOURCE=number", pair)
+		}
+		values[strings.TrimSpace(resource)] = parsed
+	}
+	return values, nil
+}
+
+// serveCommand serves the explorer data over http, with -mine the miner runs in the same process
+func serveCommand(options *options, args []string) int {
+	flags := newFlags("serve", "")
+	listen := flags.String("listen", web.DefaultAddress, "address of the web server")
+	mine := flags.Bool("mine", false, "also mine with the wallet")
+	mining := miningFlags(flags)
+	if code, ok := parse(flags, args, 0, 0); !ok {
+		return code
+	}
+	if !*mine {
+		return serve(options, options.connectExplorer(), *listen)
+	}
+	return runMiner(options, mining, *listen)
+}
+
+// mineCommand mines with an account of the wallet, with -serve the web server runs in the same process
+func mineCommand(options *options, args []string) int {
+	flags := newFlags("mine", "")
+	listen := flags.String("serve", "", "also serve the explorer data on this address, for example "+web.DefaultAddress)
+	mining := miningFlags(flags)
+	if code, ok := parse(flags, args, 0, 0); !ok {
+		return code
+	}
+	return runMiner(options, mining, *listen)
+}
+
+// serve runs the web server until it fails or the command is interrupted
+func serve(options *options, explorer *explorer.Explorer, listen string) int {
+	served := make(chan error, 1)
+	go func() {
+		served <- web.Listen(explorer, listen)
+	}()
+	select {
+	case err := <-served:
+		return failed("Error serving the explorer data", err)
+	case <-options.interrupted.Done():
+		return exitInterrupted
+	}
+}
+
+// runMiner mines forever, and serves the explorer data when listen is not empty
+func runMiner(options *options, mining *mining, listen string) int {
+	weights, err := resources(*mining.weights)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	goals, err := resources(*mining.goals)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+
+	v, accounts, err := options.accounts()
+	if err != nil {
+		return failed("Error opening the wallet", err)
+	}
+	account, err := accounts.Account(*mining.account)
+	if err == wallet.ErrNoDefaultAccount && len(accounts.Names()) == 0 {
+		// first run
+		account, err = accounts.Create("default")
+	}
+	if err != nil {
+		return failed("Error loading the account", err)
+	}
+	// the key stays in the account, the vault is not needed anymore
+	v.Lock()
+
+	explorer := options.connectExplorer()
+	game := options.connectGame()
+	m, err := miner.New(explorer, game, account, miner.Config{
+		Workers:       *mining.workers,
+		Strategy:      *mining.strategy,
+		Weights:       weights,
+		Goals:         goals,
+		Claim:         *mining.claim,
+		DataDir:       options.dataDir,
+		Confirmations: *mining.confirmations,
+	})
+	if errors.Is(err, miner.ErrInvalidConfig) {
+		fmt.Fprintln(os.Stderr, "Error configuring the miner :", err)
+		return exitUsage
+	}
+	if err != nil {
+		return failed("Error starting the miner", err)
+	}
+
+	go m.Run()
+	if listen == "" {
+		// the claims in progress stay in the journal and are resumed by the next run
+		<-options.interrupted.Done()
+		return exitInterrupted
+	}
+	return serve(options, explorer, listen)
+}
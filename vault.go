@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"republicofminer-client-go/vault"
+	"republicofminer-client-go/wallet"
+)
+
+// archivePassword reads the password of the archives like the one of the vault, the password of a new archive is typed twice
+func archivePassword(file string, nonInteractive bool, confirm bool) wallet.Password {
+	return wallet.Password{File: file, NonInteractive: nonInteractive, Env: "ROM_ARCHIVE_PASSWORD", FileEnv: "ROM_ARCHIVE_PASSWORD_FILE", Prompt: "Archive password", Confirm: confirm}
+}
+
+// newPassword reads the new password of the vault like the current one
+func newPassword(file string, nonInteractive bool) wallet.Password {
+	return wallet.Password{File: file, NonInteractive: nonInteractive, Env: "ROM_NEW_VAULT_PASSWORD", FileEnv: "ROM_NEW_VAULT_PASSWORD_FILE", Prompt: "New vault password", Confirm: true}
+}
+
+const vaultUsage = `usage: republicofminer [global options] vault command [options] [arguments]
+
+commands:
+  list                      list the items of the vault
+  change-password           encrypt the vault with the new password from -new-password-file, ROM_NEW_VAULT_PASSWORD or the prompt
+  export [-o file]          export the items in an archive encrypted with its own password
+  import [-conflict] file   import the items of an archive, the conflicts are skipped, overwritten or renamed
+`
+
+// vaultCommand lists the items, changes the password, exports the vault in an encrypted archive or imports an archive in the vault
+func vaultCommand(options *options, args []string) int {
+	commands := map[string]bool{"list": true, "change-password": true, "export": true, "import": true}
+	if len(args) > 0 && isHelp(args[0]) {
+		fmt.Fprint(os.Stderr, vaultUsage)
+		return exitOK
+	}
+	if len(args) == 0 || !commands[args[0]] {
+		fmt.Fprint(os.Stderr, vaultUsage)
+		return exitUsage
+	}
+	command := args[0]
+	flags := newFlags("vault "+command, "")
+	archiveFile := flags.String("archive-password-file", "", "file containing the password of the archive")
+	newFile := flags.String("new-password-file", "", "file containing the new password of the vault")
+	output := flags.String("o", "-", "archive written by export")
+	conflict := flags.String("conflict", string(vault.Skip), "what import does with the existing items : skip, overwrite or rename")
+	arguments := 0
+	if command == "import" {
+		arguments = 1
+	}
+	if code, ok := parse(flags, args[1:], arguments, arguments); !ok {
+		return code
+	}
+
+	v, err := options.vault()
+	if err != nil {
+		return failed("Error unlocking the vault", err)
+	}
+	defer v.Lock()
+
+	if command == "list" {
+		items, err := v.List()
+		if err != nil {
+			return failed("Error listing the items", err)
+		}
+		for _, item := range items {
+			fmt.Println(item)
+		}
+		return exitOK
+	}
+
+	if command == "change-password" {
+		current, err := options.password()
+		if err != nil {
+			return failed("Error reading the vault password", err)
+		}
+		password, err := options.prompt(newPassword(*newFile, options.nonInteractive))
+		if err != nil {
+			return failed("Error reading the new vault password", err)
+		}
+		if err := v.ChangePassword(current, password); err != nil {
+			return failed("Error changing the password", err)
+		}
+		return exitOK
+	}
+
+	password, err := options.prompt(archivePassword(*archiveFile, options.nonInteractive, command == "export"))
+	if err != nil {
+		return failed("Error reading the archive password", err)
+	}
+
+	if command == "export" {
+		if *output == "-" {
+			if err := v.Export(os.Stdout, password); err != nil {
+				return failed("Error exporting the vault", err)
+			}
+			return exitOK
+		}
+		if err := exportFile(v, *output, password); err != nil {
+			return failed("Error exporting the vault", err)
+		}
+		return exitOK
+	}
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return failed("Error opening the archive", err)
+	}
+	defer file.Close()
+	report, err := wallet.ImportArchive(v, file, password, vault.Conflict(*conflict))
+	if err != nil {
+		return failed("Error importing the archive", err)
+	}
+	fmt.Println("Imported :", report.Imported)
+	fmt.Println("Skipped :", report.Skipped)
+	fmt.Println("Overwritten :", report.Overwritten)
+	for item, renamed := range report.Renamed {
+		fmt.Println("Renamed :", item, "->", renamed)
+	}
+	return exitOK
+}
+
+// exportFile writes the archive in a temporary file renamed to the path once complete, a failed export leaves no file
+func exportFile(v *vault.Vault, path string, password string) error {
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("the archive %s already exists", path)
+	}
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if err := v.Export(file, password); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	if err := os.Rename(file.Name(), path); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package vault
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	// ARCHIVEFORMAT identifies the archives written by Export
+	ARCHIVEFORMAT = "republicofminer-vault-archive"
+	// ARCHIVEVERSION is the version of the archives written by Export
+	ARCHIVEVERSION = 1
+)
+
+// ErrInvalidArchive is returned when the archive cannot be decrypted with the password or was altered
+var ErrInvalidArchive = errors.New("vault: wrong archive password or altered archive")
+
+// Conflict tells what Import does with an item that already exists in the vault
+type Conflict string
+
+const (
+	// Skip keeps the item of the vault
+	Skip Conflict = "skip"
+	// Overwrite replaces the item of the vault with the imported one
+	Overwrite Conflict = "overwrite"
+	// RenameImported imports the item under a free name item.1, item.2, ...
+	RenameImported Conflict = "rename"
+)
+
+// ArchiveHeader is the metadata of an archive, it is authenticated with the items
+type ArchiveHeader struct {
+	Format  string
+	Version int
+	Created time.Time
+	Items   int
+	KDF     KDF
+}
+
+// Archive is a portable copy of the items of a vault encrypted with the key derived from its own password
+type Archive struct {
+	ArchiveHeader
+	// Encrypted is the json of the items sealed like the items of a vault, the header is the additional data
+	Encrypted []byte
+}
+
+// ImportReport lists what Import did with the items of the archive
+type ImportReport struct {
+	Imported    []string
+	Skipped     []string
+	Overwritten []string
+	// Renamed maps the name in the archive to the name in the vault
+	Renamed map[string]string
+}
+
+// Export writes the items of the vault in an archive encrypted with the password
+// the password of the archive may differ from the one of the vault
+func (vault *Vault) Export(w io.Writer, password string) error {
+	if err := vault.CheckDatabase(); err != nil {
+		return err
+	}
+
+	encrypted, err := vault.database.Items()
+	if err != nil {
+		return err
+	}
+	items := map[string][]byte{}
+	for item, data := range encrypted {
+		if reserved(item) != nil {
+			continue
+		}
+		if items[item], err = vault.decrypt(data); err != nil {
+			return fmt.Errorf("vault: cannot decrypt the item %s : %v", item, err)
+		}
+	}
+
+	kdf, err := newKDF()
+	if err != nil {
+		return err
+	}
+	key, err := kdf.Key(password)
+	if err != nil {
+		return err
+	}
+	archive := &Archive{ArchiveHeader: ArchiveHeader{
+		Format:  ARCHIVEFORMAT,
+		Version: ARCHIVEVERSION,
+		Created: time.Now().UTC(),
+		Items:   len(items),
+		KDF:     *kdf,
+	}}
+	plaintext, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+	if archive.Encrypted, err = seal(key, plaintext, archive.additionalData()); err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(archive)
+}
+
+// ReadArchive decrypts the items of the archive with its password
+func ReadArchive(r io.Reader, password string) (*ArchiveHeader, map[string][]byte, error) {
+	archive := &Archive{}
+	if err := json.NewDecoder(r).Decode(archive); err != nil {
+		return nil, nil, fmt.Errorf("vault: cannot read the archive : %v", err)
+	}
+	if archive.Format != ARCHIVEFORMAT || archive.Version != ARCHIVEVERSION {
+		return nil, nil, fmt.Errorf("vault: unknown archive %s version %d", archive.Format, archive.Version)
+	}
+	if _, err := readKDF(archive.KDF.bytes()); err != nil {
+		return nil, nil, err
+	}
+
+	key, err := archive.KDF.Key(password)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(archive.Encrypted) == 0 || archive.Encrypted[0] != VERSION {
+		return nil, nil, ErrInvalidArchive
+	}
+	plaintext, err := openWith(key, archive.Encrypted[1:], archive.additionalData())
+	if err != nil {
+		return nil, nil, ErrInvalidArchive
+	}
+
+	items := map[string][]byte{}
+	if err := json.Unmarshal(plaintext, &items); err != nil {
+		return nil, nil, fmt.Errorf("vault: cannot read the items of the archive : %v", err)
+	}
+	if len(items) != archive.Items {
+		return nil, nil, ErrInvalidArchive
+	}
+	return &archive.ArchiveHeader, items, nil
+}
+
+// Import saves the items of the archive in the vault, all at once
+func (vault *Vault) Import(r io.Reader, password string, conflict Conflict) (*ImportReport, error) {
+	if err := vault.CheckDatabase(); err != nil {
+		return nil, err
+	}
+	if conflict != Skip && conflict != Overwrite && conflict != RenameImported {
+		return nil, fmt.Errorf("vault: unknown conflict mode %s", conflict)
+	}
+
+	_, items, err := ReadArchive(r, password)
+	if err != nil {
+		return nil, err
+	}
+	names, err := vault.database.Names()
+	if err != nil {
+		return nil, err
+	}
+	existing := map[string]bool{}
+	for _, name := range names {
+		existing[name] = true
+	}
+
+	report := &ImportReport{Renamed: map[string]string{}}
+	encrypted := map[string][]byte{}
+	for _, item := range sortedNames(items) {
+		if err := reserved(item); err != nil {
+			return nil, err
+		}
+		name := item
+		if existing[item] {
+			switch conflict {
+			case Skip:
+				report.Skipped = append(report.Skipped, item)
+				continue
+			case Overwrite:
+				report.Overwritten = append(report.Overwritten, item)
+			case RenameImported:
+				name = free(item, existing)
+				report.Renamed[item] = name
+			}
+		} else {
+			report.Imported = append(report.Imported, item)
+		}
+		existing[name] = true
+		encrypted[name] = vault.encrypt(items[item])
+	}
+
+	if err := vault.database.SetItems(encrypted); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// additionalData is the header authenticated with the items
+func (archive *Archive) additionalData() []byte {
+	data, _ := json.Marshal(archive.ArchiveHeader)
+	return data
+}
+
+// free returns the first name item.1, item.2, ... that is not used
+func free(item string, existing map[string]bool) string {
+	for index := 1; ; index++ {
+		name := fmt.Sprintf("%s.%d", item, index)
+		if !existing[name] {
+			return name
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package vault
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestArchive(t *testing.T) {
+	eachBackend(t, func(t *testing.T, backend string, path string) {
+		source, _ := unlock(backend, path+"-source", "thisisapassword")
+		source.Save("wallet", []byte("the private key"))
+		source.Save("mnemonic", []byte("the words"))
+
+		archive := &bytes.Buffer{}
+		if err := source.Export(archive, "thisisthearchivepassword"); err != nil {
+			t.Fatal("Error exporting the vault :", err)
+		}
+		if bytes.Contains(archive.Bytes(), []byte("the private key")) {
+			t.Fatal("the archive should be encrypted")
+		}
+
+		header, items, err := ReadArchive(bytes.NewReader(archive.Bytes()), "thisisthearchivepassword")
+		if err != nil || header.Items != 2 || len(header.KDF.Salt) != 32 || header.Created.IsZero() {
+			t.Fatal("expected the metadata of 2 items, actual", header, err)
+		}
+		if string(items["wallet"]) != "the private key" || string(items["mnemonic"]) != "the words" {
+			t.Fatal("expected the items of the vault, actual", items)
+		}
+		if _, _, err := ReadArchive(bytes.NewReader(archive.Bytes()), "thisisthevaultpassword"); err != ErrInvalidArchive {
+			t.Fatal("expected :", ErrInvalidArchive, "actual", err)
+		}
+
+		// the metadata is authenticated
+		altered := &Archive{}
+		json.Unmarshal(archive.Bytes(), altered)
+		altered.Items = 3
+		encoded, _ := json.Marshal(altered)
+		if _, _, err := ReadArchive(bytes.NewReader(encoded), "thisisthearchivepassword"); err != ErrInvalidArchive {
+			t.Fatal("expected :", ErrInvalidArchive, "actual", err)
+		}
+
+		// the cost of the key derivation is bounded
+		for _, kdf := range []KDF{{N: maxN * 2, R: 8, P: 1}, {N: 1 << 15, R: maxR + 1, P: 1}, {N: 1 << 15, R: 8, P: maxP + 1}, {N: 1000, R: 8, P: 1}} {
+			costly := &Archive{}
+			json.Unmarshal(archive.Bytes(), costly)
+			costly.KDF.N, costly.KDF.R, costly.KDF.P = kdf.N, kdf.R, kdf.P
+			encoded, _ := json.Marshal(costly)
+			if _, _, err := ReadArchive(bytes.NewReader(encoded), "thisisthearchivepassword"); err == nil || err == ErrInvalidArchive {
+				t.Fatal("expected : invalid key derivation parameters actual", err)
+			}
+		}
+
+		expected := map[Conflict]map[string]string{
+			Skip:           {"wallet": "another key", "mnemonic": "the words"},
+			Overwrite:      {"wallet": "the private key", "mnemonic": "the words"},
+			RenameImported: {"wallet": "another key", "wallet.1": "the private key", "mnemonic": "the words"},
+		}
+		for conflict, content := range expected {
+			destination, _ := unlock(backend, path+"-"+string(conflict), "anotherpassword")
+			destination.Save("wallet", []byte("another key"))
+
+			report, err := destination.Import(bytes.NewReader(archive.Bytes()), "thisisthearchivepassword", conflict)
+			if err != nil {
+				t.Fatal("Error importing the archive :", err)
+			}
+			if len(report.Imported) != 1 || report.Imported[0] != "mnemonic" {
+				t.Fatal(conflict, "expected : [mnemonic] imported actual", report.Imported)
+			}
+			names, _ := destination.List()
+			if len(names) != len(content) {
+				t.Fatal(conflict, "expected :", content, "actual", names)
+			}
+			for item, value := range content {
+				if loaded, err := destination.Load(item); err != nil || string(loaded) != value {
+					t.Fatal(conflict, item, "expected :", value, "actual", string(loaded), err)
+				}
+			}
+		}
+	})
+}
@@ -1,8 +1,9 @@
+//go:build cgo
+
 package vault
 
 import (
 	"database/sql"
-	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -14,36 +15,46 @@ const (
 	tablescript = "CREATE TABLE `encrypteditems` (`item` VARCHAR(64) PRIMARY KEY, `encrypted` BLOB NOT NULL);"
 )
 
+// sqlite opens the SQLite storage, the backend is only built with cgo
+var sqlite Backend = func(path string) (Storage, error) {
+	database, err := Database(path)
+	if err != nil {
+		return nil, err
+	}
+	return database, nil
+}
+
+// DefaultBackend is the backend of Unlock, SQLite when built with cgo
+const DefaultBackend = SQLITE
+
+// VaultDatabase is the SQLite storage of the items
 type VaultDatabase struct {
 	path string
 }
 
-func Database(path string) *VaultDatabase {
-	db := VaultDatabase{fmt.Sprintf("%s.db", path)}
-	db.initialize()
-	return &db
+// Database opens the SQLite database path.db, the table is created the first time
+func Database(path string) (*VaultDatabase, error) {
+	db := &VaultDatabase{path + extensions[SQLITE]}
+	if err := db.initialize(); err != nil {
+		return nil, fmt.Errorf("vault: cannot open the database %s : %w", db.path, err)
+	}
+	return db, nil
 }
 
 func (vault *VaultDatabase) initialize() error {
-	path := vault.path
-	// check db exists
-	db, err := sql.Open("sqlite3", path)
-
-	// check tables
-	rows, err := db.Query("SELECT 1 FROM encrypteditems LIMIT 1;")
-	if err != nil {
+	return vault.transaction(func(db *sql.DB) error {
+		// check tables
+		rows, err := db.Query("SELECT 1 FROM encrypteditems LIMIT 1;")
+		if err == nil {
+			return rows.Close()
+		}
 		// create tables
-		_, err = db.Exec(tablescript)
-		checkErr(err)
+		if _, err := db.Exec(tablescript); err != nil {
+			return err
+		}
 		log.Println("tables created")
-
-	} else {
-		rows.Close() //good habit to close
-	}
-
-	db.Close()
-
-	return err
+		return nil
+	})
 }
 
 func (vault *VaultDatabase) transaction(callback func(db *sql.DB) error) error {
@@ -60,32 +71,120 @@ func (vault *VaultDatabase) Item(item string) ([]byte, error) {
 
 	var encrypted []byte
 	err := vault.transaction(func(db *sql.DB) error {
-		rows, e := db.Query("SELECT encrypted FROM encrypteditems WHERE item = ? LIMIT 1", item)
-		checkErr(e)
+		rows, err := db.Query("SELECT encrypted FROM encrypteditems WHERE item = ? LIMIT 1", item)
+		if err != nil {
+			return err
+		}
 		defer rows.Close() //good habit to close
 
 		for rows.Next() {
 			return rows.Scan(&encrypted)
 		}
-
-		return errors.New("Should have 1 row")
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return ErrItemNotFound
 	})
 	return encrypted, err
 }
 
 func (vault *VaultDatabase) SetItem(item string, encrypted []byte) error {
 	return vault.transaction(func(db *sql.DB) error {
-		_, err := db.Exec("INSERT INTO encrypteditems(item, encrypted) values(?,?)", item, encrypted)
+		_, err := db.Exec("INSERT OR REPLACE INTO encrypteditems(item, encrypted) values(?,?)", item, encrypted)
 		return err
 	})
 }
 
-func (vault *VaultDatabase) Delete() {
-	os.Remove(vault.path)
+// Names lists the names of the items in alphabetical order
+func (vault *VaultDatabase) Names() ([]string, error) {
+	names := []string{}
+	err := vault.transaction(func(db *sql.DB) error {
+		rows, err := db.Query("SELECT item FROM encrypteditems ORDER BY item")
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				return err
+			}
+			names = append(names, name)
+		}
+		return rows.Err()
+	})
+	return names, err
 }
 
-func checkErr(err error) {
-	if err != nil {
-		panic(err)
-	}
+// RenameItem changes the name of an item, the new name should not be used
+func (vault *VaultDatabase) RenameItem(item string, renamed string) error {
+	return vault.transaction(func(db *sql.DB) error {
+		var exists int
+		if err := db.QueryRow("SELECT COUNT(*) FROM encrypteditems WHERE item = ?", renamed).Scan(&exists); err != nil {
+			return err
+		}
+		if exists > 0 {
+			return fmt.Errorf("vault: the item %s already exists", renamed)
+		}
+		result, err := db.Exec("UPDATE encrypteditems SET item = ? WHERE item = ?", renamed, item)
+		if err != nil {
+			return err
+		}
+		if updated, _ := result.RowsAffected(); updated == 0 {
+			return ErrItemNotFound
+		}
+		return nil
+	})
+}
+
+// Items returns the encrypted content of every item
+func (vault *VaultDatabase) Items() (map[string][]byte, error) {
+	items := map[string][]byte{}
+	err := vault.transaction(func(db *sql.DB) error {
+		rows, err := db.Query("SELECT item, encrypted FROM encrypteditems")
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var item string
+			var encrypted []byte
+			if err := rows.Scan(&item, &encrypted); err != nil {
+				return err
+			}
+			items[item] = encrypted
+		}
+		return rows.Err()
+	})
+	return items, err
+}
+
+// SetItems inserts or replaces the items in a single transaction
+func (vault *VaultDatabase) SetItems(items map[string][]byte) error {
+	return vault.transaction(func(db *sql.DB) error {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		for item, encrypted := range items {
+			if _, err := tx.Exec("INSERT OR REPLACE INTO encrypteditems(item, encrypted) values(?,?)", item, encrypted); err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+		return tx.Commit()
+	})
+}
+
+func (vault *VaultDatabase) DeleteItem(item string) error {
+	return vault.transaction(func(db *sql.DB) error {
+		_, err := db.Exec("DELETE FROM encrypteditems WHERE item = ?", item)
+		return err
+	})
+}
+
+func (vault *VaultDatabase) Delete() {
+	os.Remove(vault.path)
 }
@@ -0,0 +1,117 @@
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// FileStorage keeps the encrypted items in a JSON file, the file is replaced atomically on every change
+type FileStorage struct {
+	path  string
+	mutex sync.Mutex
+}
+
+// File returns the storage of the file path.json, the file is created by the first change
+func File(path string) *FileStorage {
+	return &FileStorage{path: path + extensions[FILE]}
+}
+
+func (storage *FileStorage) Item(item string) ([]byte, error) {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	items, err := storage.load()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, ok := items[item]
+	if !ok {
+		return nil, ErrItemNotFound
+	}
+	return encrypted, nil
+}
+
+func (storage *FileStorage) SetItem(item string, encrypted []byte) error {
+	return storage.SetItems(map[string][]byte{item: encrypted})
+}
+
+func (storage *FileStorage) SetItems(changed map[string][]byte) error {
+	return storage.update(func(items map[string][]byte) error {
+		for item, encrypted := range changed {
+			items[item] = encrypted
+		}
+		return nil
+	})
+}
+
+func (storage *FileStorage) Items() (map[string][]byte, error) {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	return storage.load()
+}
+
+func (storage *FileStorage) Names() ([]string, error) {
+	items, err := storage.Items()
+	if err != nil {
+		return nil, err
+	}
+	return sortedNames(items), nil
+}
+
+func (storage *FileStorage) RenameItem(item string, renamed string) error {
+	return storage.update(func(items map[string][]byte) error {
+		return rename(items, item, renamed)
+	})
+}
+
+func (storage *FileStorage) DeleteItem(item string) error {
+	return storage.update(func(items map[string][]byte) error {
+		delete(items, item)
+		return nil
+	})
+}
+
+func (storage *FileStorage) Delete() {
+	os.Remove(storage.path)
+}
+
+// update changes the items then replaces the file
+func (storage *FileStorage) update(change func(items map[string][]byte) error) error {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	items, err := storage.load()
+	if err != nil {
+		return err
+	}
+	if err := change(items); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(items, "", "  ")
+	if err != nil {
+		return err
+	}
+	// a crash leaves either the old or the new file
+	temporary := storage.path + ".tmp"
+	if err := os.WriteFile(temporary, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(temporary, storage.path)
+}
+
+// load reads the items of the file, a missing file has no item
+func (storage *FileStorage) load() (map[string][]byte, error) {
+	items := map[string][]byte{}
+	data, err := os.ReadFile(storage.path)
+	if os.IsNotExist(err) {
+		return items, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("vault: cannot read %s : %v", storage.path, err)
+	}
+	return items, nil
+}
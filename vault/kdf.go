@@ -0,0 +1,77 @@
+package vault
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// KDFITEM is the item storing the parameters of the key derivation in clear
+	KDFITEM = "kdf"
+
+	// VERSION is the first byte of the encrypted items, the items of the legacy vaults have no version
+	VERSION byte = 1
+
+	// SCRYPT is the only derivation function of the version 1
+	SCRYPT = "scrypt"
+)
+
+// KDF holds the parameters of the derivation of the key from the password
+type KDF struct {
+	Version   byte
+	Algorithm string
+	Salt      []byte
+	N         int
+	R         int
+	P         int
+}
+
+// the highest costs accepted from a database or an archive, a little above DefaultKDF
+// an altered file cannot ask for more than 256 MB (128 * N * R) and 32 times the work of DefaultKDF
+const (
+	maxN = 1 << 18
+	maxR = 8
+	maxP = 4
+)
+
+// DefaultKDF is the cost of the new vaults, about 100 ms and 32 MB to derive the key
+var DefaultKDF = KDF{Version: VERSION, Algorithm: SCRYPT, N: 1 << 15, R: 8, P: 1}
+
+// newKDF creates the parameters of a vault with a random salt
+func newKDF() (*KDF, error) {
+	kdf := DefaultKDF
+	kdf.Salt = make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, kdf.Salt); err != nil {
+		return nil, err
+	}
+	return &kdf, nil
+}
+
+// readKDF decodes the parameters stored in the database
+func readKDF(data []byte) (*KDF, error) {
+	kdf := &KDF{}
+	if err := json.Unmarshal(data, kdf); err != nil {
+		return nil, fmt.Errorf("vault: cannot read the key derivation parameters : %v", err)
+	}
+	if kdf.Version != VERSION || kdf.Algorithm != SCRYPT {
+		return nil, fmt.Errorf("vault: unknown key derivation %s version %d", kdf.Algorithm, kdf.Version)
+	}
+	if kdf.N < 2 || kdf.N > maxN || kdf.N&(kdf.N-1) != 0 || kdf.R < 1 || kdf.R > maxR || kdf.P < 1 || kdf.P > maxP || len(kdf.Salt) == 0 {
+		return nil, fmt.Errorf("vault: invalid key derivation parameters N %d R %d P %d", kdf.N, kdf.R, kdf.P)
+	}
+	return kdf, nil
+}
+
+// Key derives the AES key of the password
+func (kdf *KDF) Key(password string) ([]byte, error) {
+	return scrypt.Key([]byte(password), kdf.Salt, kdf.N, kdf.R, kdf.P, 32)
+}
+
+func (kdf *KDF) bytes() []byte {
+	data, _ := json.Marshal(kdf)
+	return data
+}
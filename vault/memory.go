@@ -0,0 +1,89 @@
+package vault
+
+import (
+	"fmt"
+	"sync"
+)
+
+// MemoryStorage keeps the items in memory, it is lost with the storage
+type MemoryStorage struct {
+	mutex sync.Mutex
+	items map[string][]byte
+}
+
+// Memory returns an empty storage
+func Memory() *MemoryStorage {
+	return &MemoryStorage{items: map[string][]byte{}}
+}
+
+func (storage *MemoryStorage) Item(item string) ([]byte, error) {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	encrypted, ok := storage.items[item]
+	if !ok {
+		return nil, ErrItemNotFound
+	}
+	return append([]byte(nil), encrypted...), nil
+}
+
+func (storage *MemoryStorage) SetItem(item string, encrypted []byte) error {
+	return storage.SetItems(map[string][]byte{item: encrypted})
+}
+
+func (storage *MemoryStorage) SetItems(items map[string][]byte) error {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	for item, encrypted := range items {
+		storage.items[item] = append([]byte(nil), encrypted...)
+	}
+	return nil
+}
+
+func (storage *MemoryStorage) Items() (map[string][]byte, error) {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	items := map[string][]byte{}
+	for item, encrypted := range storage.items {
+		items[item] = append([]byte(nil), encrypted...)
+	}
+	return items, nil
+}
+
+func (storage *MemoryStorage) Names() ([]string, error) {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	return sortedNames(storage.items), nil
+}
+
+func (storage *MemoryStorage) RenameItem(item string, renamed string) error {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	return rename(storage.items, item, renamed)
+}
+
+func (storage *MemoryStorage) DeleteItem(item string) error {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	delete(storage.items, item)
+	return nil
+}
+
+func (storage *MemoryStorage) Delete() {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	storage.items = map[string][]byte{}
+}
+
+// rename moves the item of the map
+func rename(items map[string][]byte, item string, renamed string) error {
+	encrypted, ok := items[item]
+	if !ok {
+		return ErrItemNotFound
+	}
+	if _, ok := items[renamed]; ok {
+		return fmt.Errorf("vault: the item %s already exists", renamed)
+	}
+	delete(items, item)
+	items[renamed] = encrypted
+	return nil
+}
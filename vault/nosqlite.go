@@ -0,0 +1,9 @@
+//go:build !cgo
+
+package vault
+
+// sqlite is missing, the SQLite driver needs cgo
+var sqlite Backend
+
+// DefaultBackend is the backend of Unlock, the JSON file when built without cgo
+const DefaultBackend = FILE
@@ -0,0 +1,114 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// ErrItemNotFound is returned when the storage has no item with the name
+var ErrItemNotFound = errors.New("vault: item not found")
+
+// ErrOtherBackend is returned when the backend has no vault at the path but another backend has one
+var ErrOtherBackend = errors.New("vault: the vault is stored by another backend")
+
+// Storage keeps the encrypted items of a vault, the vault encrypts them before
+type Storage interface {
+	// Item returns the item or ErrItemNotFound
+	Item(item string) ([]byte, error)
+	// SetItem inserts or replaces the item
+	SetItem(item string, encrypted []byte) error
+	// SetItems inserts or replaces all the items at once, none of them is changed if it fails
+	SetItems(items map[string][]byte) error
+	// Items returns every item
+	Items() (map[string][]byte, error)
+	// Names lists the names of the items in alphabetical order
+	Names() ([]string, error)
+	// RenameItem changes the name of an item, the new name should not be used
+	RenameItem(item string, renamed string) error
+	// DeleteItem removes the item, removing a missing item is not an error
+	DeleteItem(item string) error
+	// Delete removes the whole storage
+	Delete()
+}
+
+// Backend opens the storage at the path, each backend adds its own extension
+type Backend func(path string) (Storage, error)
+
+const (
+	// SQLITE stores the vault in the SQLite database path.db, only when built with cgo
+	SQLITE = "sqlite"
+	// FILE stores the vault in the JSON file path.json
+	FILE = "file"
+	// MEMORY keeps the vault in memory, every opening starts an empty vault
+	MEMORY = "memory"
+)
+
+// extensions are the extensions of the files of the backends, known even when the backend is not built
+var extensions = map[string]string{SQLITE: ".db", FILE: ".json"}
+
+// backends returns the backends built in the binary
+func backends() map[string]Backend {
+	backends := map[string]Backend{
+		FILE:   func(path string) (Storage, error) { return File(path), nil },
+		MEMORY: func(string) (Storage, error) { return Memory(), nil },
+	}
+	if sqlite != nil {
+		backends[SQLITE] = sqlite
+	}
+	return backends
+}
+
+// Backends lists the names of the backends built in the binary
+func Backends() []string {
+	names := []string{}
+	for name := range backends() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// OpenStorage opens the storage of the backend at the path
+func OpenStorage(backend string, path string) (Storage, error) {
+	open, ok := backends()[backend]
+	if !ok {
+		return nil, fmt.Errorf("vault: unknown storage backend %s, available : %v", backend, Backends())
+	}
+	if err := stored(backend, path); err != nil {
+		return nil, err
+	}
+	return open(path)
+}
+
+// stored fails when the backend has no file at the path but another backend has one
+// otherwise opening the vault with the wrong backend would create a new empty vault next to the existing one
+func stored(backend string, path string) error {
+	extension, ok := extensions[backend]
+	if !ok {
+		return nil
+	}
+	if _, err := os.Stat(path + extension); err == nil {
+		return nil
+	}
+	for other, found := range extensions {
+		if other == backend {
+			continue
+		}
+		if _, err := os.Stat(path + found); err == nil {
+			return fmt.Errorf("%w : %s%s exists, use the %s backend", ErrOtherBackend, path, found, other)
+		}
+	}
+	return nil
+}
+
+// sortedNames returns the names of the items in alphabetical order
+func sortedNames(items map[string][]byte) []string {
+	names := make([]string, 0, len(items))
+	for name := range items {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
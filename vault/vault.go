@@ -1,4 +1,4 @@
-// This package stores encrypted data in a sql database
+// This package stores encrypted data in a sql database, a JSON file or the memory
 package vault
 
 import (
@@ -6,8 +6,10 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"republicofminer-client-go/crypto"
 )
 
@@ -16,77 +18,320 @@ const (
 	CHECKSTRING = "this is a string to check"
 )
 
-// Unlock will unlock the target vault for future use
-func Unlock(name, password string) bool {
-	database = Database(name)
-	// get the sample
-	check, err := database.Item(CHECKITEM)
+// Vault stores the items of a database encrypted with the key derived from the password
+type Vault struct {
+	database Storage
+	secret   []byte
+}
+
+// ErrWrongPassword is returned when the password does not decrypt the vault
+var ErrWrongPassword = errors.New("vault: the password does not match")
+
+// Unlock will unlock the vault at the path with the default backend
+func Unlock(path, password string) (*Vault, error) {
+	return UnlockWith(DefaultBackend, path, password)
+}
+
+// UnlockWith will unlock the vault at the path with the storage backend
+func UnlockWith(backend string, path string, password string) (*Vault, error) {
+	storage, err := OpenStorage(backend, path)
+	if err != nil {
+		return nil, err
+	}
+	return UnlockStorage(storage, password)
+}
+
+// UnlockStorage will unlock the target vault for future use, a new vault is locked with the password
+// the legacy vaults keyed with the keccak of the password are migrated to the key derivation
+func UnlockStorage(storage Storage, password string) (*Vault, error) {
+	vault := &Vault{database: storage}
+	// get the sample, only a missing one makes a new vault, a failing storage should never replace it
+	check, err := vault.database.Item(CHECKITEM)
+	if errors.Is(err, ErrItemNotFound) {
+		if err := vault.create(password); err != nil {
+			return nil, err
+		}
+		return vault, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	params, err := vault.database.Item(KDFITEM)
+	if errors.Is(err, ErrItemNotFound) {
+		return vault.migrate(password, check)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	kdf, err := readKDF(params)
+	if err != nil {
+		return nil, err
+	}
+	if vault.secret, err = kdf.Key(password); err != nil {
+		return nil, err
+	}
+	decrypted, err := vault.decrypt(check)
+	if err != nil || !bytes.Equal(decrypted, []byte(CHECKSTRING)) {
+		return nil, ErrWrongPassword
+	}
+	return vault, nil
+}
+
+// create locks the new vault with the key derived from the password
+func (vault *Vault) create(password string) error {
+	kdf, err := newKDF()
+	if err != nil {
+		return err
+	}
+	if vault.secret, err = kdf.Key(password); err != nil {
+		return err
+	}
+	return vault.database.SetItems(map[string][]byte{
+		KDFITEM:   kdf.bytes(),
+		CHECKITEM: vault.encrypt([]byte(CHECKSTRING)),
+	})
+}
 
-	secret = crypto.Keccak256([]byte(password))
+// migrate checks the password of a legacy vault and encrypts all its items again with the key derivation
+func (vault *Vault) migrate(password string, check []byte) (*Vault, error) {
+	legacy := crypto.Keccak256([]byte(password))
+	decrypted, err := open(legacy, check)
+	if err != nil || !bytes.Equal(decrypted, []byte(CHECKSTRING)) {
+		return nil, ErrWrongPassword
+	}
 
+	items, err := vault.database.Items()
+	if err != nil {
+		return nil, err
+	}
+	delete(items, KDFITEM)
+	kdf, err := newKDF()
 	if err != nil {
-		database.SetItem(CHECKITEM, encrypt([]byte(CHECKSTRING)))
-		return true
-	} else {
-		if bytes.Compare(decrypt(check), []byte(CHECKSTRING)) == 0 {
-			return true
+		return nil, err
+	}
+	if vault.secret, err = kdf.Key(password); err != nil {
+		return nil, err
+	}
+
+	migrated := map[string][]byte{KDFITEM: kdf.bytes()}
+	for item, encrypted := range items {
+		plaintext, err := open(legacy, encrypted)
+		if err != nil {
+			return nil, fmt.Errorf("vault: cannot migrate the item %s : %v", item, err)
 		}
-		fmt.Println("The pasword does not match")
-		return false
+		migrated[item] = vault.encrypt(plaintext)
+	}
+	// all the items are replaced at once, an interrupted migration leaves the legacy vault
+	if err := vault.database.SetItems(migrated); err != nil {
+		return nil, err
 	}
+	log.Println("Migrated the vault to", SCRYPT)
+	return vault, nil
 }
 
-var database *VaultDatabase
-var secret []byte
+// ErrLocked is returned when the vault is used after Lock
+var ErrLocked = errors.New("vault: the vault is locked")
 
-// CheckDatabase : Check if connected to database
-// TODO IsUnlocked
-func CheckDatabase() error {
+// CheckDatabase : Check if connected to database and unlocked
+func (vault *Vault) CheckDatabase() error {
+	if vault.database == nil || !vault.IsUnlocked() {
+		return ErrLocked
+	}
 	return nil
 }
 
+// IsUnlocked tells if the vault holds the key to decrypt the items
+func (vault *Vault) IsUnlocked() bool {
+	return vault.secret != nil
+}
+
+// Lock wipes the key from the memory, Unlock the vault again to use it
+func (vault *Vault) Lock() {
+	for index := range vault.secret {
+		vault.secret[index] = 0
+	}
+	vault.secret = nil
+}
+
 // Load will load and decrypt the requested item from the database
-func Load(item string) ([]byte, error) {
-	if err := CheckDatabase(); err != nil {
+func (vault *Vault) Load(item string) ([]byte, error) {
+	if err := vault.CheckDatabase(); err != nil {
 		return nil, err
 	}
-	data, err := database.Item(item)
+	data, err := vault.database.Item(item)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return decrypt(data), nil
+	return vault.decrypt(data)
 }
 
 // Save will save and encrypt the requested item in the database
-func Save(item string, bytes []byte) error {
-	if err := CheckDatabase(); err != nil {
+func (vault *Vault) Save(item string, bytes []byte) error {
+	if err := vault.CheckDatabase(); err != nil {
 		return err
 	}
 
-	return database.SetItem(item, encrypt([]byte(bytes)))
+	if err := reserved(item); err != nil {
+		return err
+	}
+	// an existing item is replaced
+	return vault.database.SetItem(item, vault.encrypt([]byte(bytes)))
 }
 
-func encrypt(plaintext []byte) []byte {
-	block, err := aes.NewCipher(secret)
+// Remove will delete the requested item from the database
+func (vault *Vault) Remove(item string) error {
+	if err := vault.CheckDatabase(); err != nil {
+		return err
+	}
+
+	if err := reserved(item); err != nil {
+		return err
+	}
+	return vault.database.DeleteItem(item)
+}
+
+// List will list the names of the items saved in the database
+func (vault *Vault) List() ([]string, error) {
+	if err := vault.CheckDatabase(); err != nil {
+		return nil, err
+	}
+
+	names, err := vault.database.Names()
 	if err != nil {
-		panic(err.Error())
+		return nil, err
+	}
+	items := []string{}
+	for _, name := range names {
+		if reserved(name) == nil {
+			items = append(items, name)
+		}
 	}
+	return items, nil
+}
 
-	gcm, err := cipher.NewGCM(block)
+// Rename will change the name of the requested item, the new name should not be used
+func (vault *Vault) Rename(item string, renamed string) error {
+	if err := vault.CheckDatabase(); err != nil {
+		return err
+	}
+
+	if err := reserved(item); err != nil {
+		return err
+	}
+	if err := reserved(renamed); err != nil {
+		return err
+	}
+	return vault.database.RenameItem(item, renamed)
+}
+
+// ChangePassword will encrypt every item with the key derived from the new password with a new salt
+// the items are replaced in a single transaction, the vault keeps the old password if it fails
+func (vault *Vault) ChangePassword(current string, password string) error {
+	if err := vault.CheckDatabase(); err != nil {
+		return err
+	}
+
+	params, err := vault.database.Item(KDFITEM)
 	if err != nil {
-		panic(err.Error())
+		return err
 	}
-	nonce := make([]byte, gcm.NonceSize())
-	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+	kdf, err := readKDF(params)
+	if err != nil {
+		return err
+	}
+	secret, err := kdf.Key(current)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(secret, vault.secret) {
+		return ErrWrongPassword
+	}
+
+	items, err := vault.database.Items()
+	if err != nil {
+		return err
+	}
+	delete(items, KDFITEM)
+	if kdf, err = newKDF(); err != nil {
+		return err
+	}
+	changed := &Vault{database: vault.database}
+	if changed.secret, err = kdf.Key(password); err != nil {
+		return err
+	}
+
+	encrypted := map[string][]byte{KDFITEM: kdf.bytes()}
+	for item, data := range items {
+		plaintext, err := vault.decrypt(data)
+		if err != nil {
+			return fmt.Errorf("vault: cannot decrypt the item %s : %v", item, err)
+		}
+		encrypted[item] = changed.encrypt(plaintext)
+	}
+	if err := vault.database.SetItems(encrypted); err != nil {
+		return err
+	}
+
+	vault.Lock()
+	vault.secret = changed.secret
+	return nil
+}
+
+// reserved tells if the item is used by the vault itself
+func reserved(item string) error {
+	if item == CHECKITEM || item == KDFITEM {
+		return fmt.Errorf("vault: the item %s is reserved", item)
+	}
+	return nil
+}
+
+// encrypt returns the version, the nonce and the sealed plaintext
+func (vault *Vault) encrypt(plaintext []byte) []byte {
+	ciphertext, err := seal(vault.secret, plaintext, nil)
+	if err != nil {
 		panic(err.Error())
 	}
-	ciphertext := gcm.Seal(nonce, nonce, plaintext, nil)
 	return ciphertext
 }
 
-func decrypt(cyphertext []byte) []byte {
+// seal returns the version, the nonce and the plaintext sealed with the additional data
+func seal(secret []byte, plaintext []byte, additionalData []byte) ([]byte, error) {
+	gcm := newGCM(secret)
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(append([]byte{VERSION}, nonce...), nonce, plaintext, additionalData), nil
+}
+
+func (vault *Vault) decrypt(cyphertext []byte) ([]byte, error) {
+	if len(cyphertext) == 0 || cyphertext[0] != VERSION {
+		return nil, errors.New("vault: unknown version of the encrypted item")
+	}
+	return open(vault.secret, cyphertext[1:])
+}
+
+// open decrypts the nonce and the sealed plaintext, the legacy items have no version
+func open(secret []byte, cyphertext []byte) ([]byte, error) {
+	return openWith(secret, cyphertext, nil)
+}
+
+// openWith decrypts the nonce and the plaintext sealed with the additional data
+func openWith(secret []byte, cyphertext []byte, additionalData []byte) ([]byte, error) {
+	gcm := newGCM(secret)
+	nonceSize := gcm.NonceSize()
+	if len(cyphertext) < nonceSize {
+		return nil, errors.New("vault: the encrypted item is truncated")
+	}
+	nonce, ciphertext := cyphertext[:nonceSize], cyphertext[nonceSize:]
+	return gcm.Open(nil, nonce, ciphertext, additionalData)
+}
+
+func newGCM(secret []byte) cipher.AEAD {
 	block, err := aes.NewCipher(secret)
 	if err != nil {
 		panic(err.Error())
@@ -95,16 +340,14 @@ func decrypt(cyphertext []byte) []byte {
 	if err != nil {
 		panic(err.Error())
 	}
-	nonceSize := gcm.NonceSize()
-	nonce, ciphertext := cyphertext[:nonceSize], cyphertext[nonceSize:]
-	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
-	if err != nil {
-		panic(err.Error())
-	}
-	return plaintext
+	return gcm
 }
 
-func delete() {
-	database = nil
-	database.Delete()
+// delete removes the database file and locks the vault
+func (vault *Vault) delete() {
+	if vault.database != nil {
+		vault.database.Delete()
+	}
+	vault.database = nil
+	vault.Lock()
 }
@@ -2,51 +2,287 @@ package vault
 
 import (
 	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
 	"republicofminer-client-go/crypto"
+	"sync"
 	"testing"
 )
 
 func TestEncryptDecypt(t *testing.T) {
 
-	secret = crypto.Keccak256([]byte("ansdfsd45f141as41fas1ds1f1"))
+	vault := &Vault{secret: crypto.Keccak256([]byte("ansdfsd45f141as41fas1ds1f1"))}
 	plaintext := []byte("as4da1dd4qd4s1ad7qd54q1d541q4w1d45q154d")
-	encrypted := encrypt(plaintext)
-	decrypted := decrypt(encrypted)
+	encrypted := vault.encrypt(plaintext)
+	decrypted, err := vault.decrypt(encrypted)
 
-	if bytes.Equal(encrypted, decrypted) {
+	if err != nil || bytes.Equal(encrypted, plaintext) || !bytes.Equal(plaintext, decrypted) {
 		t.Errorf("encrypt + decrypt does not work")
 	}
 }
 
-func TestVault(t *testing.T) {
-	name := "someitem"
-	content := []byte("some important stuff")
-	ok := Unlock("test", "thisisapassword")
+// memories keeps the memory storages of the tests by path, the memory backend opens an empty storage every time
+var memories = struct {
+	sync.Mutex
+	storages map[string]Storage
+}{storages: map[string]Storage{}}
 
-	if !ok {
-		t.Errorf("could not unlock an empty vault")
-		return
+// openStorage is OpenStorage where the memory storage of a path is found again
+func openStorage(backend string, path string) (Storage, error) {
+	if backend != MEMORY {
+		return OpenStorage(backend, path)
+	}
+	memories.Lock()
+	defer memories.Unlock()
+	if _, ok := memories.storages[path]; !ok {
+		memories.storages[path] = Memory()
 	}
+	return memories.storages[path], nil
+}
 
-	item, err := Load(name)
+// unlock is UnlockWith where the memory vault of a path is found again
+func unlock(backend string, path string, password string) (*Vault, error) {
+	storage, err := openStorage(backend, path)
 	if err != nil {
-		t.Errorf("the vault database should be empty")
-		return
+		return nil, err
 	}
+	return UnlockStorage(storage, password)
+}
 
-	err = Save(name, content)
-	if err != nil {
-		t.Errorf("error saving an item in the vault")
-		return
+// eachBackend runs the test with every storage backend in a temporary directory
+func eachBackend(t *testing.T, test func(t *testing.T, backend string, path string)) {
+	for _, backend := range Backends() {
+		t.Run(backend, func(t *testing.T) {
+			test(t, backend, filepath.Join(t.TempDir(), "vault"))
+		})
+	}
+}
+
+func TestVault(t *testing.T) {
+	eachBackend(t, func(t *testing.T, backend string, path string) {
+		name := "someitem"
+		content := []byte("some important stuff")
+		vault, err := unlock(backend, path, "thisisapassword")
+
+		if err != nil {
+			t.Errorf("could not unlock an empty vault")
+			return
+		}
+
+		item, err := vault.Load(name)
+		if err != ErrItemNotFound {
+			t.Errorf("the vault database should be empty")
+			return
+		}
+
+		err = vault.Save(name, content)
+		if err != nil {
+			t.Errorf("error saving an item in the vault")
+			return
+		}
+
+		item, err = vault.Load(name)
+		if err != nil {
+			t.Errorf("the item should be in the vault database")
+			return
+		}
+
+		if !bytes.Equal(content, item) {
+			t.Errorf("encrypt + decrypt does not work")
+		}
+	})
+}
+
+func TestWrongPassword(t *testing.T) {
+	eachBackend(t, func(t *testing.T, backend string, path string) {
+		if _, err := unlock(backend, path, "thisisapassword"); err != nil {
+			t.Fatal("Error creating the vault :", err)
+		}
+		if _, err := unlock(backend, path, "thisisnotthepassword"); err != ErrWrongPassword {
+			t.Fatal("expected :", ErrWrongPassword, "actual", err)
+		}
+		if _, err := unlock(backend, path, "thisisapassword"); err != nil {
+			t.Fatal("Error unlocking the vault :", err)
+		}
+	})
+}
+
+// legacy encrypts the plaintext like the vaults before the key derivation
+func legacy(password string, plaintext []byte) []byte {
+	gcm := newGCM(crypto.Keccak256([]byte(password)))
+	nonce := make([]byte, gcm.NonceSize())
+	return gcm.Seal(nonce, nonce, plaintext, nil)
+}
+
+func TestMigrateLegacyVault(t *testing.T) {
+	eachBackend(t, func(t *testing.T, backend string, path string) {
+		database, _ := openStorage(backend, path)
+		database.SetItem(CHECKITEM, legacy("thisisapassword", []byte(CHECKSTRING)))
+		database.SetItem("wallet", legacy("thisisapassword", []byte("the private key")))
+
+		if _, err := unlock(backend, path, "thisisnotthepassword"); err != ErrWrongPassword {
+			t.Fatal("expected :", ErrWrongPassword, "actual", err)
+		}
+		if _, err := database.Item(KDFITEM); err == nil {
+			t.Fatal("a wrong password should not migrate the vault")
+		}
+
+		vault, err := unlock(backend, path, "thisisapassword")
+		if err != nil {
+			t.Fatal("Error migrating the vault :", err)
+		}
+		params, err := database.Item(KDFITEM)
+		if err != nil {
+			t.Fatal("the key derivation parameters should be stored :", err)
+		}
+		kdf, err := readKDF(params)
+		if err != nil || len(kdf.Salt) != 32 || kdf.N != DefaultKDF.N {
+			t.Fatal("expected the default parameters and a salt, actual", kdf, err)
+		}
+		encrypted, _ := database.Item("wallet")
+		if encrypted[0] != VERSION {
+			t.Fatal("the migrated items should have a version, actual", encrypted[0])
+		}
+
+		// the migrated vault unlocks with the derived key
+		vault, err = unlock(backend, path, "thisisapassword")
+		if err != nil {
+			t.Fatal("Error unlocking the migrated vault :", err)
+		}
+		if item, err := vault.Load("wallet"); err != nil || string(item) != "the private key" {
+			t.Fatal("expected : the private key actual", string(item), err)
+		}
+		if err := vault.Save(KDFITEM, nil); err == nil {
+			t.Fatal("the key derivation parameters should not be overwritten")
+		}
+	})
+}
+
+func TestVaultItems(t *testing.T) {
+	eachBackend(t, func(t *testing.T, backend string, path string) {
+		vault, err := unlock(backend, path, "thisisapassword")
+		if err != nil {
+			t.Fatal("Error creating the vault :", err)
+		}
+		vault.Save("first", []byte("first"))
+		vault.Save("second", []byte("second"))
+		if err := vault.Save("first", []byte("overwritten")); err != nil {
+			t.Fatal("Error overwriting the item :", err)
+		}
+		if item, _ := vault.Load("first"); string(item) != "overwritten" {
+			t.Fatal("expected : overwritten actual", string(item))
+		}
+
+		if err := vault.Rename("second", "first"); err == nil {
+			t.Fatal("the renamed item should not replace another item")
+		}
+		if err := vault.Rename("missing", "third"); err != ErrItemNotFound {
+			t.Fatal("expected :", ErrItemNotFound, "actual", err)
+		}
+		if err := vault.Rename("second", "third"); err != nil {
+			t.Fatal("Error renaming the item :", err)
+		}
+		if err := vault.Remove("first"); err != nil {
+			t.Fatal("Error deleting the item :", err)
+		}
+		if _, err := vault.Load("first"); err != ErrItemNotFound {
+			t.Fatal("expected :", ErrItemNotFound, "actual", err)
+		}
+		if names, err := vault.List(); err != nil || len(names) != 1 || names[0] != "third" {
+			t.Fatal("expected : [third] actual", names, err)
+		}
+		if err := vault.Remove(CHECKITEM); err == nil {
+			t.Fatal("the check item should not be deleted")
+		}
+	})
+}
+
+func TestChangePassword(t *testing.T) {
+	eachBackend(t, func(t *testing.T, backend string, path string) {
+		vault, _ := unlock(backend, path, "thisisapassword")
+		vault.Save("item", []byte("some important stuff"))
+
+		if err := vault.ChangePassword("thisisnotthepassword", "thisisthenewpassword"); err != ErrWrongPassword {
+			t.Fatal("expected :", ErrWrongPassword, "actual", err)
+		}
+		if err := vault.ChangePassword("thisisapassword", "thisisthenewpassword"); err != nil {
+			t.Fatal("Error changing the password :", err)
+		}
+		if item, err := vault.Load("item"); err != nil || string(item) != "some important stuff" {
+			t.Fatal("the vault should use the new key, actual", string(item), err)
+		}
+
+		if _, err := unlock(backend, path, "thisisapassword"); err != ErrWrongPassword {
+			t.Fatal("expected :", ErrWrongPassword, "actual", err)
+		}
+		reopened, err := unlock(backend, path, "thisisthenewpassword")
+		if err != nil {
+			t.Fatal("Error unlocking with the new password :", err)
+		}
+		if item, err := reopened.Load("item"); err != nil || string(item) != "some important stuff" {
+			t.Fatal("expected : some important stuff actual", string(item), err)
+		}
+
+		reopened.Lock()
+		if reopened.IsUnlocked() {
+			t.Fatal("the vault should be locked")
+		}
+		if _, err := reopened.Load("item"); err != ErrLocked {
+			t.Fatal("expected :", ErrLocked, "actual", err)
+		}
+		if err := reopened.Save("item", nil); err != ErrLocked {
+			t.Fatal("expected :", ErrLocked, "actual", err)
+		}
+	})
+}
+
+func TestOtherBackend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vault")
+	// an existing vault of the backend not built, like SQLite without cgo
+	if err := os.WriteFile(path+extensions[SQLITE], []byte("sqlite"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := UnlockWith(FILE, path, "thisisapassword"); !errors.Is(err, ErrOtherBackend) {
+		t.Fatal("expected :", ErrOtherBackend, "actual", err)
+	}
+	if _, err := os.Stat(path + extensions[FILE]); !os.IsNotExist(err) {
+		t.Fatal("no vault should be created next to the existing one")
 	}
+	if _, err := UnlockWith(MEMORY, path, "thisisapassword"); err != nil {
+		t.Fatal("the memory backend has no file :", err)
+	}
+}
+
+// failingStorage cannot read the items, like a database that is busy or a file that cannot be opened
+type failingStorage struct {
+	*MemoryStorage
+	failing string
+}
 
-	item, err = Load(name)
-	if err == nil {
-		t.Errorf("the item should be in the vault database")
-		return
+func (storage *failingStorage) Item(item string) ([]byte, error) {
+	if item == storage.failing {
+		return nil, errors.New("the storage is not available")
 	}
+	return storage.MemoryStorage.Item(item)
+}
 
-	if bytes.Equal(content, item) {
-		t.Errorf("encrypt + decrypt does not work")
+func TestFailingStorage(t *testing.T) {
+	memory := Memory()
+
+	if vault, err := UnlockStorage(&failingStorage{memory, CHECKITEM}, "thisisapassword"); err == nil || vault != nil {
+		t.Fatal("the vault should not open when the check cannot be read, actual", vault, err)
+	}
+	if names, _ := memory.Names(); len(names) != 0 {
+		t.Fatal("a failing storage should not create a vault, actual", names)
+	}
+
+	UnlockStorage(memory, "thisisapassword")
+	kdf, _ := memory.Item(KDFITEM)
+	if _, err := UnlockStorage(&failingStorage{memory, KDFITEM}, "thisisapassword"); err == nil {
+		t.Fatal("the vault should not be migrated when the key derivation cannot be read")
+	}
+	if current, _ := memory.Item(KDFITEM); !bytes.Equal(current, kdf) {
+		t.Fatal("the key derivation should not be replaced")
 	}
 }
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"republicofminer-client-go/explorer/api"
+	"republicofminer-client-go/verify"
+)
+
+// verifyCommand verifies the transaction and signatures of the file, or of the standard input without argument
+// the file holds a SendTransactionRequest, the exit code is 0 when valid, 1 when invalid and 2 when it cannot be read
+func verifyCommand(args []string) int {
+	flags := newFlags("verify", "[file]")
+	if code, ok := parse(flags, args, 0, 1); !ok {
+		return code
+	}
+	var input io.Reader = os.Stdin
+	if flags.NArg() == 1 && flags.Arg(0) != "-" {
+		file, err := os.Open(flags.Arg(0))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error opening the transaction :", err)
+			return 2
+		}
+		defer file.Close()
+		input = file
+	}
+
+	var request api.SendTransactionRequest
+	if err := json.NewDecoder(input).Decode(&request); err != nil {
+		fmt.Fprintln(os.Stderr, "Error parsing the transaction :", err)
+		return 2
+	}
+
+	report, err := verify.Transaction(request.Transaction, request.Signatures)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error verifying the transaction :", err)
+		return 2
+	}
+
+	encoded, _ := json.MarshalIndent(report, "", "  ")
+	fmt.Println(string(encoded))
+	if !report.Valid {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,250 @@
+// The verify package checks a transaction and its signatures without the explorer
+package verify
+
+import (
+	"errors"
+	"fmt"
+	"republicofminer-client-go/explorer/api"
+	"republicofminer-client-go/protocol"
+	"republicofminer-client-go/protocol/converter/apitoprotocol"
+	"republicofminer-client-go/protocol/format/address32"
+	"sort"
+)
+
+// Status tells if an input is authorized by the transaction
+type Status string
+
+const (
+	// Authorized means the signatures or the declarations of the transaction allow to spend the input
+	Authorized Status = "authorized"
+	// Unauthorized means nothing in the transaction allows to spend the input
+	Unauthorized Status = "unauthorized"
+	// Unverified means the account is declared, we cannot derive its address offline to tell
+	Unverified Status = "unverified"
+)
+
+// Report is the detailed result of the verification of a transaction
+type Report struct {
+	// Hash is the hash we computed, HashMatches compares it with the hash of the transaction when it has one
+	Hash        string
+	HashMatches bool
+	Signatures  []*SignatureReport
+	Inputs      []*InputReport
+	Outputs     []*OutputReport
+	Balances    []*BalanceReport
+	// Complete is false when an input is unverified
+	Complete bool
+	// Valid is true when every check passed, an incomplete verification is not valid
+	Valid bool
+}
+
+// SignatureReport is the verification of one of the signatures
+type SignatureReport struct {
+	PublicKey string
+	// Address is the ECDSA address of the public key
+	Address string `json:",omitempty"`
+	Valid   bool
+	// Canonical is false when the signature has a high s, it is valid but can be altered by anyone
+	Canonical bool
+	// Used is true when the signer is needed by one of the inputs
+	Used  bool
+	Error string `json:",omitempty"`
+}
+
+// InputReport tells how an input or the fees are authorized
+type InputReport struct {
+	Address  string
+	Currency string
+	Amount   float64
+	Fees     bool `json:",omitempty"`
+	Status   Status
+	Reason   string `json:",omitempty"`
+}
+
+// OutputReport tells if the address of an output can receive the amount
+type OutputReport struct {
+	Address  string
+	Currency string
+	Amount   float64
+	Valid    bool
+	Error    string `json:",omitempty"`
+}
+
+// BalanceReport compares the inputs with the outputs and the fees of a currency
+type BalanceReport struct {
+	Currency string
+	Inputs   float64
+	Outputs  float64
+	Fees     float64
+	Balanced bool
+}
+
+// Transaction verifies the transaction and its signatures
+// it only fails when the transaction cannot be hashed, every check is reported in the Report
+// the transactions declaring accounts fail with protocol.ErrUnconfirmedDeclaration until their layout is confirmed
+// the inputs of the declared accounts are unverified, their address is never taken from the explorer
+func Transaction(transaction *api.Transaction, signatures []*api.Signature) (*Report, error) {
+	if transaction == nil {
+		return nil, errors.New("verify: missing transaction")
+	}
+	converted, err := apitoprotocol.ToTransaction(transaction)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := converted.Hash()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{Hash: hash.ToBase64()}
+	report.HashMatches = transaction.Hash == "" || transaction.Hash == report.Hash
+
+	signers := map[string]*SignatureReport{}
+	for _, signature := range signatures {
+		checked := check(hash, signature)
+		report.Signatures = append(report.Signatures, checked)
+		if checked.Valid {
+			signers[checked.Address] = checked
+		}
+	}
+
+	authorize := authorizer(signers)
+	if converted.Fees != nil {
+		input := authorize(converted.Fees)
+		input.Fees = true
+		report.Inputs = append(report.Inputs, input)
+	}
+	for _, input := range converted.Inputs {
+		report.Inputs = append(report.Inputs, authorize(input))
+	}
+	for _, output := range converted.Outputs {
+		report.Outputs = append(report.Outputs, receive(output))
+	}
+
+	report.Balances = balance(converted)
+
+	report.Complete = true
+	for _, input := range report.Inputs {
+		report.Complete = report.Complete && input.Status != Unverified
+	}
+	report.Valid = report.HashMatches && report.Complete
+	for _, signature := range report.Signatures {
+		report.Valid = report.Valid && signature.Valid
+	}
+	for _, input := range report.Inputs {
+		report.Valid = report.Valid && input.Status == Authorized
+	}
+	for _, output := range report.Outputs {
+		report.Valid = report.Valid && output.Valid
+	}
+	for _, balance := range report.Balances {
+		report.Valid = report.Valid && balance.Balanced
+	}
+	return report, nil
+}
+
+// check verifies the signature of the hash with the network prefix
+func check(hash []byte, signature *api.Signature) *SignatureReport {
+	report := &SignatureReport{PublicKey: signature.PublicKey}
+
+	key, err := protocol.PublicKeyFromBase64(signature.PublicKey)
+	if err != nil {
+		report.Error = fmt.Sprint("invalid public key : ", err)
+		return report
+	}
+	report.Address = key.GetAddress().Encoded
+
+	decoded, err := protocol.SignatureFromBase64(signature.SignatureByte)
+	if err != nil {
+		report.Error = fmt.Sprint("invalid signature : ", err)
+		return report
+	}
+
+	report.Canonical = decoded.IsCanonical()
+	report.Valid = key.CheckSignature(hash, decoded, protocol.Network)
+	if !report.Valid {
+		report.Error = "the signature does not match the public key"
+	}
+	return report
+}
+
+// authorizer returns the function that tells how an input is authorized by the signers
+func authorizer(signers map[string]*SignatureReport) func(input *protocol.TxInput) *InputReport {
+	return func(input *protocol.TxInput) *InputReport {
+		report := &InputReport{
+			Address:  input.Address.Encoded,
+			Currency: input.Currency.ToSymbol(),
+			Amount:   input.Amount.ToFloat(),
+		}
+
+		if _, _, err := address32.Decode(input.Address.Encoded); err != nil {
+			report.Status = Unauthorized
+			report.Reason = fmt.Sprint("invalid address : ", err)
+			return report
+		}
+		if input.Address.Type != protocol.ECDSA {
+			report.Status = Unverified
+			report.Reason = "the address of a declared account cannot be derived offline"
+			return report
+		}
+
+		if signature, ok := signers[input.Address.Encoded]; ok {
+			signature.Used = true
+			report.Status = Authorized
+		} else {
+			report.Status = Unauthorized
+			report.Reason = "no valid signature of the address"
+		}
+		return report
+	}
+}
+
+// receive checks the address of the output
+func receive(output *protocol.TxOutput) *OutputReport {
+	report := &OutputReport{
+		Address:  output.Address.Encoded,
+		Currency: output.Currency.ToSymbol(),
+		Amount:   output.Amount.ToFloat(),
+		Valid:    true,
+	}
+	if _, _, err := address32.Decode(output.Address.Encoded); err != nil {
+		report.Valid = false
+		report.Error = fmt.Sprint("invalid address : ", err)
+	}
+	return report
+}
+
+// balance sums the inputs, outputs and fees of each currency
+func balance(transaction *protocol.Transaction) []*BalanceReport {
+	type sums struct{ inputs, outputs, fees protocol.Amount }
+	currencies := map[protocol.Currency]*sums{}
+	get := func(currency protocol.Currency) *sums {
+		if _, ok := currencies[currency]; !ok {
+			currencies[currency] = &sums{}
+		}
+		return currencies[currency]
+	}
+
+	for _, input := range transaction.Inputs {
+		get(input.Currency).inputs += input.Amount
+	}
+	for _, output := range transaction.Outputs {
+		get(output.Currency).outputs += output.Amount
+	}
+	if transaction.Fees != nil {
+		get(transaction.Fees.Currency).fees += transaction.Fees.Amount
+	}
+
+	reports := []*BalanceReport{}
+	for currency, sum := range currencies {
+		reports = append(reports, &BalanceReport{
+			Currency: currency.ToSymbol(),
+			Inputs:   sum.inputs.ToFloat(),
+			Outputs:  sum.outputs.ToFloat(),
+			Fees:     sum.fees.ToFloat(),
+			Balanced: sum.inputs == sum.outputs+sum.fees,
+		})
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].Currency < reports[j].Currency })
+	return reports
+}
@@ -0,0 +1,220 @@
+package verify
+
+import (
+	"encoding/json"
+	"errors"
+	"republicofminer-client-go/explorer/api"
+	"republicofminer-client-go/protocol"
+	"republicofminer-client-go/protocol/converter/apitoprotocol"
+	"republicofminer-client-go/protocol/converter/protocoltoapi"
+	"testing"
+)
+
+var keys = []string{
+	"7r7oFxKhhaH7UvMLpUXlcIEk0WWx7i4nw6BVnrKCmLk=",
+	"QkQ07ERvruv0idJ6e0xDX2GbpYcuLB+dPueldNyd5xA=",
+	"cyg1lEqj83Xa00KHdtab5tCyvhKVC6q84YkEFnERbPI=",
+}
+
+func key(t *testing.T, index int) *protocol.PrivateKey {
+	key, err := protocol.PrivateKeyFromBase64(keys[index])
+	if err != nil {
+		t.Fatal("Error decoding the private key :", err)
+	}
+	return key
+}
+
+func entry(address *protocol.Address, amount float64) protocol.TxInputOutput {
+	return protocol.TxInputOutput{Address: *address, Currency: protocol.CurrencyFromSymbol("IRO"), Amount: protocol.AmountFromFloat(amount)}
+}
+
+// sign converts the transaction to the api and signs it with the keys
+func sign(t *testing.T, transaction *protocol.Transaction, signers ...*protocol.PrivateKey) (*api.Transaction, []*api.Signature) {
+	hash, err := transaction.Hash()
+	if err != nil {
+		t.Fatal("Error hashing the transaction :", err)
+	}
+	signatures := []*api.Signature{}
+	for _, signer := range signers {
+		signature, err := signer.SignMessage(hash, protocol.Network)
+		if err != nil {
+			t.Fatal("Error signing the transaction :", err)
+		}
+		signatures = append(signatures, &api.Signature{PublicKey: signer.GetPublicKey().ToBase64(), SignatureByte: signature.ToBase64()})
+	}
+	converted, err := protocoltoapi.ToTransaction(transaction)
+	if err != nil {
+		t.Fatal("Error converting the transaction :", err)
+	}
+	return converted, signatures
+}
+
+func payment(t *testing.T) *protocol.Transaction {
+	sender := key(t, 0).GetPublicKey().GetAddress()
+	receiver := key(t, 1).GetPublicKey().GetAddress()
+	fees := protocol.TxInput(entry(sender, 0.1))
+	input := protocol.TxInput(entry(sender, 2))
+	output := protocol.TxOutput(entry(receiver, 1.9))
+	return &protocol.Transaction{Expire: 1560404881, Fees: &fees, Inputs: []*protocol.TxInput{&input}, Outputs: []*protocol.TxOutput{&output}}
+}
+
+func TestValidTransaction(t *testing.T) {
+	transaction, signatures := sign(t, payment(t), key(t, 0))
+
+	report, err := Transaction(transaction, signatures)
+	if err != nil {
+		t.Fatal("Error verifying the transaction :", err)
+	}
+	if !report.Valid || !report.Complete || !report.HashMatches || report.Hash != transaction.Hash {
+		t.Fatalf("the transaction should be valid : %+v", report)
+	}
+	if len(report.Inputs) != 2 || !report.Inputs[0].Fees || report.Inputs[1].Status != Authorized {
+		t.Fatalf("the fees and the input should be authorized : %+v %+v", report.Inputs[0], report.Inputs[1])
+	}
+	if !report.Signatures[0].Used || !report.Signatures[0].Canonical || report.Signatures[0].Address != key(t, 0).GetPublicKey().GetAddress().Encoded {
+		t.Fatalf("the signature should be used by the inputs : %+v", report.Signatures[0])
+	}
+	if len(report.Balances) != 1 || !report.Balances[0].Balanced || report.Balances[0].Fees != 0.1 {
+		t.Fatalf("the transaction should balance : %+v", report.Balances[0])
+	}
+}
+
+func TestTamperedTransaction(t *testing.T) {
+	transaction, signatures := sign(t, payment(t), key(t, 0))
+	transaction.Outputs[0].Amount = 2
+
+	report, err := Transaction(transaction, signatures)
+	if err != nil {
+		t.Fatal("Error verifying the transaction :", err)
+	}
+	if report.Valid || report.HashMatches || report.Signatures[0].Valid || report.Balances[0].Balanced {
+		t.Fatalf("the hash, the signature and the balance should fail : %+v", report)
+	}
+}
+
+func TestWrongSigner(t *testing.T) {
+	transaction, signatures := sign(t, payment(t), key(t, 1))
+
+	report, _ := Transaction(transaction, signatures)
+	if report.Valid || !report.Signatures[0].Valid || report.Signatures[0].Used {
+		t.Fatalf("the signature is valid but does not authorize the inputs : %+v", report.Signatures[0])
+	}
+	for _, input := range report.Inputs {
+		if input.Status != Unauthorized {
+			t.Fatalf("the input should not be authorized : %+v", input)
+		}
+	}
+}
+
+func TestInvalidSignature(t *testing.T) {
+	transaction, signatures := sign(t, payment(t), key(t, 0))
+	signatures[0].SignatureByte = "AAAA"
+
+	report, _ := Transaction(transaction, signatures)
+	if report.Valid || report.Signatures[0].Valid || report.Signatures[0].Error == "" {
+		t.Fatalf("the signature should be invalid : %+v", report.Signatures[0])
+	}
+}
+
+func TestDeclaredAccounts(t *testing.T) {
+	multi := &protocol.MultiSignature{Signers: []protocol.Address{*key(t, 0).GetPublicKey().GetAddress(), *key(t, 1).GetPublicKey().GetAddress()}, Required: 2}
+	shared := protocol.CreateAddress(protocol.MultiSignatureECDSA, make([]byte, 20))
+	sender := key(t, 0).GetPublicKey().GetAddress()
+	receiver := key(t, 1).GetPublicKey().GetAddress()
+
+	first := protocol.TxInput(entry(shared, 1))
+	second := protocol.TxInput(entry(sender, 1))
+	output := protocol.TxOutput(entry(receiver, 2))
+	transaction := &protocol.Transaction{Expire: 1560404881, Inputs: []*protocol.TxInput{&first, &second}, Outputs: []*protocol.TxOutput{&output}}
+
+	// the account declared in another transaction
+	converted, signatures := sign(t, transaction, key(t, 0), key(t, 1))
+	report, err := Transaction(converted, signatures)
+	if err != nil {
+		t.Fatal("Error verifying the transaction :", err)
+	}
+	if report.Inputs[0].Status != Unverified || report.Inputs[1].Status != Authorized {
+		t.Fatalf("the declared account should be unverified : %+v %+v", report.Inputs[0], report.Inputs[1])
+	}
+	if report.Complete || report.Valid {
+		t.Fatalf("an unverified input should not be valid : %+v", report)
+	}
+
+	// the layouts of the declared accounts are not confirmed, the transaction cannot be hashed
+	transaction.Declarations = []*protocol.TxDeclaration{&protocol.TxDeclaration{Type: protocol.TxMultiSignature, Declaration: multi}}
+	declared, err := protocoltoapi.ToTransaction(transaction)
+	if err != nil {
+		t.Fatal("Error converting the transaction :", err)
+	}
+	if _, err := Transaction(declared, nil); !errors.Is(err, protocol.ErrUnconfirmedDeclaration) {
+		t.Fatal("expected :", protocol.ErrUnconfirmedDeclaration, "actual", err)
+	}
+}
+
+// the sha256 hash lock goes through the api and is unlocked by its secret, but its layout is not confirmed to be hashed
+func TestSHA256HashLock(t *testing.T) {
+	secret := protocol.SecretFromBase64("NXYBRplyY/bDfjBzVNppa/PzPvIDlOxK3j3urVVh4Jk=")
+	hashlock, err := protocol.NewHashLock(protocol.SHA256, secret)
+	if err != nil {
+		t.Fatal("Error creating the hash lock :", err)
+	}
+	transaction := &protocol.Transaction{Expire: 1560404881, Declarations: []*protocol.TxDeclaration{
+		&protocol.TxDeclaration{Type: protocol.TxHashLock, Declaration: hashlock},
+		&protocol.TxDeclaration{Type: protocol.TxSecret, Declaration: protocol.NewSecretRevelation(secret)},
+	}}
+
+	converted, err := protocoltoapi.ToTransaction(transaction)
+	if err != nil {
+		t.Fatal("Error converting the transaction :", err)
+	}
+	encoded, _ := json.Marshal(converted)
+	var decoded api.Transaction
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatal("Error decoding the transaction :", err)
+	}
+	back, err := apitoprotocol.ToTransaction(&decoded)
+	if err != nil {
+		t.Fatal("Error converting the transaction back :", err)
+	}
+	lock, ok := back.Declarations[0].Declaration.(*protocol.HashLockDeclaration)
+	revelation, _ := back.Declarations[1].Declaration.(*protocol.SecretRevelation)
+	if !ok || lock.SecretHash.Type != protocol.SHA256 || revelation == nil || !lock.Unlocks(revelation) {
+		t.Fatalf("the secret should unlock the sha256 hash lock : %+v", back.Declarations[0].Declaration)
+	}
+
+	if _, err := Transaction(&decoded, nil); !errors.Is(err, protocol.ErrUnconfirmedDeclaration) {
+		t.Fatal("expected :", protocol.ErrUnconfirmedDeclaration, "actual", err)
+	}
+}
+
+func TestInvalidAddresses(t *testing.T) {
+	transaction, signatures := sign(t, payment(t), key(t, 0))
+	transaction.Hash = ""
+	transaction.Inputs[0].Address = "zzz"
+	transaction.Outputs[0].Address = "zzz"
+
+	report, err := Transaction(transaction, signatures)
+	if err != nil {
+		t.Fatal("Error verifying the transaction :", err)
+	}
+	if report.Valid || report.Inputs[1].Status != Unauthorized || report.Inputs[1].Reason == "" {
+		t.Fatalf("the input of an invalid address should not be authorized : %+v", report.Inputs[1])
+	}
+	if report.Outputs[0].Valid || report.Outputs[0].Error == "" {
+		t.Fatalf("the output of an invalid address should be invalid : %+v", report.Outputs[0])
+	}
+}
+
+func TestMissingExpire(t *testing.T) {
+	if _, err := Transaction(&api.Transaction{}, nil); err == nil {
+		t.Fatal("a transaction without expiration cannot be hashed")
+	}
+}
+
+func TestMissingInput(t *testing.T) {
+	var request api.SendTransactionRequest
+	json.Unmarshal([]byte(`{"Transaction":{"Expire":1,"Inputs":[null]}}`), &request)
+	if _, err := Transaction(request.Transaction, nil); err == nil {
+		t.Fatal("a transaction with a missing input cannot be hashed")
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"republicofminer-client-go/wallet"
+	"strings"
+)
+
+// PassphraseEnv is the environment variable holding the optional passphrase of the mnemonic
+const PassphraseEnv = "ROM_MNEMONIC_PASSPHRASE"
+
+const walletUsage = `usage: republicofminer [global options] wallet command [arguments]
+
+commands:
+  list                  list the accounts and their address
+  create name           create an account with a new key
+  import name           import the base64 private key read from the standard input
+  export name           print the base64 private key of the account
+  rename name renamed   rename the account
+  delete name           delete the account and its key, export it before
+  default name          choose the default account
+  mnemonic              generate the mnemonic of the wallet, write it down
+  restore               restore the mnemonic read from the standard input
+  derive [options]      list the addresses derived from the mnemonic, -save imports one
+`
+
+// walletCommand manages the accounts and the mnemonic of the wallet
+func walletCommand(options *options, args []string) int {
+	arguments := map[string]int{"list": 0, "create": 1, "import": 1, "export": 1, "rename": 2, "delete": 1, "default": 1, "mnemonic": 0, "restore": 0}
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, walletUsage)
+		return exitUsage
+	}
+	if isHelp(args[0]) {
+		fmt.Fprint(os.Stderr, walletUsage)
+		return exitOK
+	}
+	if args[0] == "derive" {
+		return deriveCommand(options, args[1:])
+	}
+	count, ok := arguments[args[0]]
+	if !ok || len(args)-1 != count {
+		fmt.Fprint(os.Stderr, walletUsage)
+		return exitUsage
+	}
+
+	v, accounts, err := options.accounts()
+	if err != nil {
+		return failed("Error opening the wallet", err)
+	}
+	defer v.Lock()
+
+	switch args[0] {
+	case "list":
+		printAccounts(accounts)
+		return exitOK
+	case "create":
+		account, err := accounts.Create(args[1])
+		if err != nil {
+			return failed("Error creating the account", err)
+		}
+		fmt.Println(account.Address.Encoded)
+	case "import":
+		encoded, err := readLine()
+		if err != nil {
+			return failed("Error reading the private key", err)
+		}
+		account, err := accounts.Import(args[1], encoded)
+		if err != nil {
+			return failed("Error importing the account", err)
+		}
+		fmt.Println(account.Address.Encoded)
+	case "export":
+		encoded, err := accounts.Export(args[1])
+		if err != nil {
+			return failed("Error exporting the account", err)
+		}
+		fmt.Println(encoded)
+	case "rename":
+		err = accounts.Rename(args[1], args[2])
+	case "delete":
+		err = accounts.Delete(args[1])
+	case "default":
+		err = accounts.SetDefault(args[1])
+	case "mnemonic":
+		hd, err := wallet.CreateHD(v, os.Getenv(PassphraseEnv))
+		if err != nil {
+			return failed("Error creating the mnemonic", err)
+		}
+		fmt.Println(hd.Mnemonic)
+	case "restore":
+		mnemonic, err := readLine()
+		if err != nil {
+			return failed("Error reading the mnemonic", err)
+		}
+		if _, err := wallet.RestoreHD(v, mnemonic, os.Getenv(PassphraseEnv)); err != nil {
+			return failed("Error restoring the mnemonic", err)
+		}
+	}
+	if err != nil {
+		return failed("Error changing the account", err)
+	}
+	return exitOK
+}
+
+// deriveCommand lists the addresses of an account of the mnemonic, or saves one of them as a named account
+func deriveCommand(options *options, args []string) int {
+	flags := newFlags("wallet derive", "")
+	account := flags.Uint("account", 0, "account of the BIP44 path")
+	count := flags.Int("count", 5, "number of addresses to list")
+	save := flags.String("save", "", "save the key of the address at -index as the named account")
+	index := flags.Uint("index", 0, "index of the address saved with -save")
+	if code, ok := parse(flags, args, 0, 0); !ok {
+		return code
+	}
+
+	v, accounts, err := options.accounts()
+	if err != nil {
+		return failed("Error opening the wallet", err)
+	}
+	defer v.Lock()
+	hd, err := wallet.LoadHD(v)
+	if err != nil {
+		return failed("Error loading the mnemonic, create or restore it first", err)
+	}
+
+	if *save != "" {
+		path := wallet.AccountPath(uint32(*account), uint32(*index))
+		derived, err := hd.Derive(path)
+		if err != nil {
+			return failed("Error deriving the key", err)
+		}
+		if _, err := accounts.Import(*save, derived.Privatekey.ToBase64()); err != nil {
+			return failed("Error saving the account", err)
+		}
+		fmt.Println(path, derived.Address.Encoded)
+		return exitOK
+	}
+
+	derivations, err := hd.Addresses(uint32(*account), *count)
+	if err != nil {
+		return failed("Error deriving the addresses", err)
+	}
+	for _, derivation := range derivations {
+		fmt.Println(derivation.Path, derivation.Address.Encoded)
+	}
+	return exitOK
+}
+
+// addressCommand prints the address of the account, the default one without argument
+func addressCommand(options *options, args []string) int {
+	flags := newFlags("address", "[account]")
+	all := flags.Bool("all", false, "list the addresses of all the accounts")
+	if code, ok := parse(flags, args, 0, 1); !ok {
+		return code
+	}
+
+	v, accounts, err := options.accounts()
+	if err != nil {
+		return failed("Error opening the wallet", err)
+	}
+	defer v.Lock()
+
+	if *all {
+		printAccounts(accounts)
+		return exitOK
+	}
+	account, err := accounts.Account(flags.Arg(0))
+	if err != nil {
+		return failed("Error loading the account", err)
+	}
+	fmt.Println(account.Address.Encoded)
+	return exitOK
+}
+
+// printAccounts prints the name and the address of the accounts, the default one is marked with a star
+func printAccounts(accounts *wallet.Accounts) {
+	chosen, _ := accounts.Default()
+	for _, name := range accounts.Names() {
+		account, _ := accounts.Account(name)
+		mark := " "
+		if account == chosen {
+			mark = "*"
+		}
+		fmt.Println(mark, name, account.Address.Encoded)
+	}
+}
+
+// readLine reads the first line of the standard input, so the secrets do not appear in the command line
+func readLine() (string, error) {
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
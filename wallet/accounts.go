@@ -0,0 +1,386 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"republicofminer-client-go/protocol"
+	"republicofminer-client-go/vault"
+	"sort"
+	"strings"
+)
+
+const (
+	// AccountsItem is the vault item listing the names of the accounts and the default one
+	AccountsItem = "accounts"
+	// the private key of an account is stored in the item prefix + name
+	accountPrefix = "account/"
+	// MaxAccountName is the longest name that fits in the items of the vault
+	MaxAccountName = 64 - len(accountPrefix)
+)
+
+var (
+	// ErrUnknownAccount is returned when no account has the name
+	ErrUnknownAccount = errors.New("wallet: unknown account")
+	// ErrAccountExists is returned when an account already has the name
+	ErrAccountExists = errors.New("wallet: the account already exists")
+	// ErrNoDefaultAccount is returned when the default account is requested but none was chosen
+	ErrNoDefaultAccount = errors.New("wallet: no default account")
+)
+
+// Accounts holds several named private keys in the vault
+type Accounts struct {
+	vault    *vault.Vault
+	index    accountIndex
+	accounts map[string]*Wallet
+}
+
+// accountIndex is the content of the AccountsItem
+type accountIndex struct {
+	Default string
+	Names   []string
+}
+
+// OpenAccounts loads the accounts of the vault
+// the key of the legacy single account wallet becomes the default account named "default"
+func OpenAccounts(v *vault.Vault) (*Accounts, error) {
+	accounts := &Accounts{vault: v, accounts: map[string]*Wallet{}}
+	data, err := v.Load(AccountsItem)
+	if errors.Is(err, vault.ErrItemNotFound) {
+		// no index yet
+		legacy, err := v.Load("wallet")
+		if errors.Is(err, vault.ErrItemNotFound) {
+			return accounts, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("wallet: cannot load the private key : %w", err)
+		}
+		if _, err := accounts.add("default", protocol.PrivateKeyFromBytes(legacy)); err != nil {
+			return nil, err
+		}
+		return accounts, nil
+	}
+	if err != nil {
+		// never replace an index we cannot read
+		return nil, fmt.Errorf("wallet: cannot load the accounts : %w", err)
+	}
+
+	if err := json.Unmarshal(data, &accounts.index); err != nil {
+		return nil, fmt.Errorf("wallet: cannot read the accounts : %v", err)
+	}
+	for _, name := range accounts.index.Names {
+		key, err := v.Load(accountPrefix + name)
+		if err != nil {
+			return nil, fmt.Errorf("wallet: cannot load the account %s : %v", name, err)
+		}
+		accounts.accounts[name] = fromKey(name, protocol.PrivateKeyFromBytes(key))
+	}
+	return accounts, nil
+}
+
+// ImportArchive imports the items of an archive in the vault then merges the accounts of the archive in the index
+// the default account of the vault stays the default one, the keys renamed by the import become the accounts name.1, name.2, ...
+// the key of an archive of a legacy single account wallet becomes an account named "default" or a free name
+func ImportArchive(v *vault.Vault, r io.Reader, password string, conflict vault.Conflict) (*vault.ImportReport, error) {
+	accounts, err := OpenAccounts(v)
+	if err != nil {
+		return nil, err
+	}
+	report, err := v.Import(r, password, conflict)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := accountIndex{Default: accounts.index.Default, Names: append([]string(nil), accounts.index.Names...)}
+	indexed := map[string]bool{}
+	for _, name := range merged.Names {
+		indexed[name] = true
+	}
+	items, err := v.List()
+	if err != nil {
+		return nil, err
+	}
+	found := []string{}
+	for _, item := range items {
+		if name := strings.TrimPrefix(item, accountPrefix); name != item && !indexed[name] {
+			found = append(found, name)
+			indexed[name] = true
+		}
+	}
+	sort.Strings(found)
+	merged.Names = append(merged.Names, found...)
+
+	if !archived(report, AccountsItem) {
+		if item, ok := imported(report, "wallet"); ok && len(merged.Names) > 0 {
+			name, err := migrateArchived(v, item, indexed)
+			if err != nil {
+				return nil, err
+			}
+			if name != "" {
+				merged.Names = append(merged.Names, name)
+			}
+		}
+	}
+	if merged.Default == "" {
+		// the index of the archive was imported as is when the vault had none
+		current := accountIndex{}
+		if data, err := v.Load(AccountsItem); err == nil && json.Unmarshal(data, &current) == nil && indexed[current.Default] {
+			merged.Default = current.Default
+		} else if len(merged.Names) > 0 {
+			merged.Default = merged.Names[0]
+		}
+	}
+	if len(merged.Names) > 0 {
+		if err := accounts.store(merged); err != nil {
+			return nil, fmt.Errorf("wallet: cannot merge the imported accounts : %w", err)
+		}
+	}
+	if renamed, ok := report.Renamed[AccountsItem]; ok {
+		// the index of the archive is merged, its renamed copy would be stale
+		if err := v.Remove(renamed); err != nil {
+			return nil, err
+		}
+		delete(report.Renamed, AccountsItem)
+	}
+	return report, nil
+}
+
+// Create generates the key of a new account
+func (accounts *Accounts) Create(name string) (*Wallet, error) {
+	return accounts.add(name, protocol.GeneratePrivateKey())
+}
+
+// Import adds an account with the private key encoded in base 64
+func (accounts *Accounts) Import(name string, encoded string) (*Wallet, error) {
+	key, err := protocol.PrivateKeyFromBase64(encoded)
+	if err != nil {
+		return nil, err
+	}
+	return accounts.add(name, key)
+}
+
+// Export returns the private key of the account encoded in base 64
+func (accounts *Accounts) Export(name string) (string, error) {
+	account, err := accounts.Account(name)
+	if err != nil {
+		return "", err
+	}
+	return account.Privatekey.ToBase64(), nil
+}
+
+// Rename changes the name of an account, it stays the default account
+func (accounts *Accounts) Rename(name string, renamed string) error {
+	account, ok := accounts.accounts[name]
+	if !ok {
+		return fmt.Errorf("%w : %s", ErrUnknownAccount, name)
+	}
+	if err := accounts.check(renamed); err != nil {
+		return err
+	}
+
+	if err := accounts.vault.Rename(accountPrefix+name, accountPrefix+renamed); err != nil {
+		return err
+	}
+	index := accounts.index
+	index.Names = replace(index.Names, name, renamed)
+	if index.Default == name {
+		index.Default = renamed
+	}
+	if err := accounts.store(index); err != nil {
+		accounts.vault.Rename(accountPrefix+renamed, accountPrefix+name)
+		return err
+	}
+
+	delete(accounts.accounts, name)
+	account.Name = renamed
+	accounts.accounts[renamed] = account
+	return nil
+}
+
+// Delete removes the account and its private key from the vault, export it before if it holds funds
+func (accounts *Accounts) Delete(name string) error {
+	if _, ok := accounts.accounts[name]; !ok {
+		return fmt.Errorf("%w : %s", ErrUnknownAccount, name)
+	}
+
+	index := accounts.index
+	index.Names = replace(index.Names, name, "")
+	if index.Default == name {
+		index.Default = ""
+	}
+	if err := accounts.store(index); err != nil {
+		return err
+	}
+	delete(accounts.accounts, name)
+	return accounts.vault.Remove(accountPrefix + name)
+}
+
+// SetDefault chooses the account used when no name is given
+func (accounts *Accounts) SetDefault(name string) error {
+	if _, ok := accounts.accounts[name]; !ok {
+		return fmt.Errorf("%w : %s", ErrUnknownAccount, name)
+	}
+	index := accounts.index
+	index.Default = name
+	return accounts.store(index)
+}
+
+// Default returns the default account
+func (accounts *Accounts) Default() (*Wallet, error) {
+	return accounts.Account("")
+}
+
+// Account returns the account with the name, the default account when the name is empty
+func (accounts *Accounts) Account(name string) (*Wallet, error) {
+	if name == "" {
+		if accounts.index.Default == "" {
+			return nil, ErrNoDefaultAccount
+		}
+		name = accounts.index.Default
+	}
+	account, ok := accounts.accounts[name]
+	if !ok {
+		return nil, fmt.Errorf("%w : %s", ErrUnknownAccount, name)
+	}
+	return account, nil
+}
+
+// Names lists the names of the accounts in alphabetical order
+func (accounts *Accounts) Names() []string {
+	names := append([]string(nil), accounts.index.Names...)
+	sort.Strings(names)
+	return names
+}
+
+// Sign signs the data with the key of the account, the default account when the name is empty
+func (accounts *Accounts) Sign(name string, data []byte) (*protocol.PublicKey, *protocol.Signature, error) {
+	account, err := accounts.Account(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return account.Sign(data)
+}
+
+// add saves the key of a new account, the first account becomes the default one
+func (accounts *Accounts) add(name string, key *protocol.PrivateKey) (*Wallet, error) {
+	if err := accounts.check(name); err != nil {
+		return nil, err
+	}
+	if err := accounts.vault.Save(accountPrefix+name, key.ToBytes()); err != nil {
+		return nil, err
+	}
+
+	index := accounts.index
+	index.Names = append(append([]string(nil), index.Names...), name)
+	if index.Default == "" {
+		index.Default = name
+	}
+	if err := accounts.store(index); err != nil {
+		accounts.vault.Remove(accountPrefix + name)
+		return nil, err
+	}
+
+	account := fromKey(name, key)
+	accounts.accounts[name] = account
+	return account, nil
+}
+
+// check tells if the name can be given to a new account
+func (accounts *Accounts) check(name string) error {
+	if name == "" || len(name) > MaxAccountName {
+		return fmt.Errorf("wallet: the name of an account should have 1 to %d characters", MaxAccountName)
+	}
+	if _, ok := accounts.accounts[name]; ok {
+		return fmt.Errorf("%w : %s", ErrAccountExists, name)
+	}
+	return nil
+}
+
+// store saves the index in the vault then keeps it
+func (accounts *Accounts) store(index accountIndex) error {
+	data, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	if err := accounts.vault.Save(AccountsItem, data); err != nil {
+		return err
+	}
+	accounts.index = index
+	return nil
+}
+
+// migrateArchived saves the key of the imported legacy wallet item as an account
+// it returns an empty name when an account already has the key
+func migrateArchived(v *vault.Vault, item string, indexed map[string]bool) (string, error) {
+	key, err := v.Load(item)
+	if err != nil {
+		return "", fmt.Errorf("wallet: cannot load the imported private key : %w", err)
+	}
+	for name := range indexed {
+		if existing, err := v.Load(accountPrefix + name); err == nil && bytes.Equal(existing, key) {
+			return "", nil
+		}
+	}
+	name := "default"
+	for index := 1; indexed[name]; index++ {
+		name = fmt.Sprintf("default.%d", index)
+	}
+	if err := v.Save(accountPrefix+name, key); err != nil {
+		return "", err
+	}
+	indexed[name] = true
+	return name, nil
+}
+
+// archived tells if the archive held the item
+func archived(report *vault.ImportReport, item string) bool {
+	if _, ok := report.Renamed[item]; ok {
+		return true
+	}
+	for _, list := range [][]string{report.Imported, report.Skipped, report.Overwritten} {
+		for _, name := range list {
+			if name == item {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// imported returns the name under which the item of the archive was saved in the vault
+func imported(report *vault.ImportReport, item string) (string, bool) {
+	if renamed, ok := report.Renamed[item]; ok {
+		return renamed, true
+	}
+	for _, list := range [][]string{report.Imported, report.Overwritten} {
+		for _, name := range list {
+			if name == item {
+				return item, true
+			}
+		}
+	}
+	return "", false
+}
+
+// replace returns a copy of the names where name is replaced, or removed when replacement is empty
+func replace(names []string, name string, replacement string) []string {
+	replaced := []string{}
+	for _, current := range names {
+		if current != name {
+			replaced = append(replaced, current)
+		} else if replacement != "" {
+			replaced = append(replaced, replacement)
+		}
+	}
+	return replaced
+}
+
+// fromKey creates the wallet of the private key
+func fromKey(name string, key *protocol.PrivateKey) *Wallet {
+	wallet := &Wallet{Name: name, Privatekey: key}
+	wallet.Publickey = key.GetPublicKey()
+	wallet.Address = wallet.Publickey.GetAddress()
+	return wallet
+}
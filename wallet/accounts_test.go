@@ -0,0 +1,206 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"republicofminer-client-go/crypto"
+	"republicofminer-client-go/protocol"
+	"republicofminer-client-go/vault"
+	"testing"
+)
+
+func TestAccounts(t *testing.T) {
+	v, _ := vault.Unlock(filepath.Join(t.TempDir(), "accounts"), "thisisapassword")
+	accounts, err := OpenAccounts(v)
+	if err != nil {
+		t.Fatal("Error opening the accounts :", err)
+	}
+	if _, err := accounts.Default(); err != ErrNoDefaultAccount {
+		t.Fatal("expected :", ErrNoDefaultAccount, "actual", err)
+	}
+
+	miner, err := accounts.Create("miner")
+	if err != nil {
+		t.Fatal("Error creating the account :", err)
+	}
+	treasury, err := accounts.Import("treasury", "7r7oFxKhhaH7UvMLpUXlcIEk0WWx7i4nw6BVnrKCmLk=")
+	if err != nil {
+		t.Fatal("Error importing the account :", err)
+	}
+	// the order of the curve, a short key and the zero key are not private keys
+	for _, invalid := range []string{"/////////////////////rqu3OavSKA7v9JejNA2QUE=", "AAAA", base64.StdEncoding.EncodeToString(make([]byte, 32))} {
+		if _, err := accounts.Import("invalid", invalid); !errors.Is(err, protocol.ErrInvalidPrivateKey) {
+			t.Fatal("expected :", protocol.ErrInvalidPrivateKey, "actual", err)
+		}
+	}
+	if _, err := accounts.Create("miner"); !errors.Is(err, ErrAccountExists) {
+		t.Fatal("expected :", ErrAccountExists, "actual", err)
+	}
+	if err := accounts.SetDefault("treasury"); err != nil {
+		t.Fatal("Error choosing the default account :", err)
+	}
+	if err := accounts.Rename("miner", "miner-1"); err != nil {
+		t.Fatal("Error renaming the account :", err)
+	}
+
+	// the accounts are saved in the vault
+	reopened, err := OpenAccounts(v)
+	if err != nil {
+		t.Fatal("Error reopening the accounts :", err)
+	}
+	if names := reopened.Names(); len(names) != 2 || names[0] != "miner-1" || names[1] != "treasury" {
+		t.Fatal("expected : [miner-1 treasury] actual", names)
+	}
+	if renamed, _ := reopened.Account("miner-1"); renamed.Address.Encoded != miner.Address.Encoded {
+		t.Fatal("the renamed account should keep its key")
+	}
+	if exported, _ := reopened.Export(""); exported != "7r7oFxKhhaH7UvMLpUXlcIEk0WWx7i4nw6BVnrKCmLk=" {
+		t.Fatal("the default account should be the imported one, actual", exported)
+	}
+
+	hash := crypto.Hash([]byte("data"))
+	for name, expected := range map[string]*Wallet{"": treasury, "miner-1": miner} {
+		pub, signature, err := reopened.Sign(name, hash)
+		if err != nil || pub.GetAddress().Encoded != expected.Address.Encoded || !expected.Publickey.CheckSignature(hash, signature, protocol.Network) {
+			t.Fatal("the account", name, "should sign, actual", err)
+		}
+	}
+
+	if err := reopened.Delete("treasury"); err != nil {
+		t.Fatal("Error deleting the account :", err)
+	}
+	if _, err := reopened.Default(); err != ErrNoDefaultAccount {
+		t.Fatal("the deleted account should not be the default one, actual", err)
+	}
+	if _, _, err := reopened.Sign("treasury", hash); !errors.Is(err, ErrUnknownAccount) {
+		t.Fatal("expected :", ErrUnknownAccount, "actual", err)
+	}
+	if _, err := v.Load(accountPrefix + "treasury"); err == nil {
+		t.Fatal("the key of the deleted account should leave the vault")
+	}
+}
+
+func TestLegacyWallet(t *testing.T) {
+	v, _ := vault.Unlock(filepath.Join(t.TempDir(), "legacy"), "thisisapassword")
+	key := protocol.GeneratePrivateKey()
+	v.Save("wallet", key.ToBytes())
+
+	accounts, err := OpenAccounts(v)
+	if err != nil {
+		t.Fatal("Error opening the accounts :", err)
+	}
+	account, err := accounts.Default()
+	if err != nil || account.Name != "default" || account.Address.Encoded != key.GetPublicKey().GetAddress().Encoded {
+		t.Fatal("the legacy key should become the default account, actual", account, err)
+	}
+}
+
+func TestImportArchive(t *testing.T) {
+	source, _ := vault.UnlockWith(vault.MEMORY, filepath.Join(t.TempDir(), "source"), "thisisapassword")
+	exported, _ := OpenAccounts(source)
+	exported.Create("treasury")
+	exported.Create("miner")
+	archive := &bytes.Buffer{}
+	if err := source.Export(archive, "thisisthearchivepassword"); err != nil {
+		t.Fatal("Error exporting the vault :", err)
+	}
+
+	expected := map[vault.Conflict][]string{
+		vault.Skip:           {"miner", "treasury"},
+		vault.Overwrite:      {"miner", "treasury"},
+		vault.RenameImported: {"miner", "treasury", "treasury.1"},
+	}
+	for conflict, names := range expected {
+		v, _ := vault.UnlockWith(vault.MEMORY, filepath.Join(t.TempDir(), string(conflict)), "anotherpassword")
+		existing, _ := OpenAccounts(v)
+		treasury, _ := existing.Create("treasury")
+
+		if _, err := ImportArchive(v, bytes.NewReader(archive.Bytes()), "thisisthearchivepassword", conflict); err != nil {
+			t.Fatal(conflict, "Error importing the archive :", err)
+		}
+		accounts, err := OpenAccounts(v)
+		if err != nil {
+			t.Fatal(conflict, "Error opening the accounts :", err)
+		}
+		if fmt.Sprint(accounts.Names()) != fmt.Sprint(names) {
+			t.Fatal(conflict, "expected :", names, "actual", accounts.Names())
+		}
+		if account, err := accounts.Default(); err != nil || account.Name != "treasury" {
+			t.Fatal(conflict, "the default account of the vault should stay the default one, actual", account, err)
+		}
+		if account, _ := accounts.Account("treasury"); (conflict == vault.Overwrite) == (account.Address.Encoded == treasury.Address.Encoded) {
+			t.Fatal(conflict, "the existing treasury should only be replaced by overwrite")
+		}
+		if items, _ := v.List(); contains(items, AccountsItem+".1") {
+			t.Fatal(conflict, "the renamed index of the archive should be merged then removed, actual", items)
+		}
+	}
+
+	// the archive of a legacy wallet
+	legacy, _ := vault.UnlockWith(vault.MEMORY, filepath.Join(t.TempDir(), "legacy"), "thisisapassword")
+	key := protocol.GeneratePrivateKey()
+	legacy.Save("wallet", key.ToBytes())
+	archive.Reset()
+	legacy.Export(archive, "thisisthearchivepassword")
+	v, _ := vault.UnlockWith(vault.MEMORY, filepath.Join(t.TempDir(), "accounts"), "anotherpassword")
+	existing, _ := OpenAccounts(v)
+	existing.Create("default")
+	if _, err := ImportArchive(v, bytes.NewReader(archive.Bytes()), "thisisthearchivepassword", vault.Skip); err != nil {
+		t.Fatal("Error importing the archive :", err)
+	}
+	accounts, _ := OpenAccounts(v)
+	if account, err := accounts.Account("default.1"); err != nil || account.Address.Encoded != key.GetPublicKey().GetAddress().Encoded {
+		t.Fatal("the legacy key of the archive should become an account, actual", accounts.Names(), err)
+	}
+}
+
+func contains(names []string, name string) bool {
+	for _, current := range names {
+		if current == name {
+			return true
+		}
+	}
+	return false
+}
+
+func TestUnreadableAccounts(t *testing.T) {
+	storage := vault.Memory()
+	v, _ := vault.UnlockStorage(storage, "thisisapassword")
+	corrupted := []byte{vault.VERSION, 1, 2, 3}
+	storage.SetItem(AccountsItem, corrupted)
+
+	if _, err := OpenAccounts(v); err == nil {
+		t.Fatal("the accounts should not open with an index we cannot decrypt")
+	}
+	if item, _ := storage.Item(AccountsItem); !bytes.Equal(item, corrupted) {
+		t.Fatal("the unreadable index should not be replaced")
+	}
+
+	storage.DeleteItem(AccountsItem)
+	storage.SetItem("wallet", corrupted)
+	if _, err := OpenAccounts(v); err == nil {
+		t.Fatal("the accounts should not open with a legacy key we cannot decrypt")
+	}
+}
+
+func TestPassword(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "password")
+	os.WriteFile(file, []byte("from the file\n"), 0600)
+	t.Setenv(PasswordFileEnv, "")
+	t.Setenv(PasswordEnv, "from the environment")
+
+	if password, err := (Password{File: file}).Get(); err != nil || password != "from the file" {
+		t.Fatal("expected : from the file actual", password, err)
+	}
+	if password, err := (Password{NonInteractive: true}).Get(); err != nil || password != "from the environment" {
+		t.Fatal("expected : from the environment actual", password, err)
+	}
+	os.Unsetenv(PasswordEnv)
+	if _, err := (Password{NonInteractive: true}).Get(); err != ErrNoPassword {
+		t.Fatal("expected :", ErrNoPassword, "actual", err)
+	}
+}
@@ -0,0 +1,175 @@
+package wallet
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"republicofminer-client-go/protocol"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/tyler-smith/go-bip39"
+)
+
+const (
+	// MnemonicItem is the vault item of the mnemonic phrase of the hierarchical deterministic wallet
+	MnemonicItem = "mnemonic"
+	// PassphraseItem is the vault item of the optional passphrase that salts the seed
+	PassphraseItem = "passphrase"
+
+	// MnemonicBits is the entropy of a new mnemonic, 256 bits make 24 words
+	MnemonicBits = 256
+
+	// Hardened is added to the index of a child derived from the private key only
+	Hardened uint32 = 0x80000000
+
+	// CoinType is the coin of the BIP44 paths, republic of miner has no registered SLIP-44 type so we use "ROM" in ASCII
+	CoinType uint32 = 0x524f4d
+)
+
+var (
+	// ErrInvalidMnemonic is returned when the words or the checksum of the mnemonic are wrong
+	ErrInvalidMnemonic = errors.New("wallet: invalid mnemonic")
+	// ErrInvalidPath is returned when a derivation path cannot be parsed
+	ErrInvalidPath = errors.New("wallet: invalid derivation path")
+	// ErrInvalidChild is returned for the rare indexes whose key is out of the curve order, the next index should be used
+	ErrInvalidChild = errors.New("wallet: the child key is invalid")
+)
+
+// the key of the HMAC of the master key defined by BIP32
+var masterSecret = []byte("Bitcoin seed")
+
+// NewMnemonic generates a random mnemonic phrase to write down
+func NewMnemonic() (string, error) {
+	entropy, err := bip39.NewEntropy(MnemonicBits)
+	if err != nil {
+		return "", err
+	}
+	return bip39.NewMnemonic(entropy)
+}
+
+// Seed checks the mnemonic and computes the BIP39 seed with the passphrase
+func Seed(mnemonic string, passphrase string) ([]byte, error) {
+	seed, err := bip39.NewSeedWithErrorChecking(strings.Join(strings.Fields(mnemonic), " "), passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("%w : %v", ErrInvalidMnemonic, err)
+	}
+	return seed, nil
+}
+
+// Path is the list of the child indexes from the master key
+type Path []uint32
+
+// AccountPath is the BIP44 path of the address of an account : m/44'/CoinType'/account'/0/index
+func AccountPath(account uint32, index uint32) Path {
+	return Path{44 + Hardened, CoinType + Hardened, account + Hardened, 0, index}
+}
+
+// ParsePath decodes a path like m/44'/5394253'/0'/0/1, the hardened indexes end with ' or h
+func ParsePath(path string) (Path, error) {
+	parts := strings.Split(strings.TrimSpace(path), "/")
+	if parts[0] != "m" {
+		return nil, fmt.Errorf("%w : %s should start with m", ErrInvalidPath, path)
+	}
+	parsed := Path{}
+	for _, part := range parts[1:] {
+		hardened := strings.HasSuffix(part, "'") || strings.HasSuffix(part, "h")
+		if hardened {
+			part = part[:len(part)-1]
+		}
+		index, err := strconv.ParseUint(part, 10, 32)
+		if err != nil || uint32(index) >= Hardened {
+			return nil, fmt.Errorf("%w : %s", ErrInvalidPath, path)
+		}
+		if hardened {
+			index += uint64(Hardened)
+		}
+		parsed = append(parsed, uint32(index))
+	}
+	return parsed, nil
+}
+
+func (path Path) String() string {
+	builder := strings.Builder{}
+	builder.WriteString("m")
+	for _, index := range path {
+		if index >= Hardened {
+			fmt.Fprintf(&builder, "/%d'", index-Hardened)
+		} else {
+			fmt.Fprintf(&builder, "/%d", index)
+		}
+	}
+	return builder.String()
+}
+
+// ExtendedKey is a BIP32 private key and the chain code used to derive its children
+type ExtendedKey struct {
+	key       []byte
+	chainCode []byte
+}
+
+// NewMasterKey derives the master key of the seed
+func NewMasterKey(seed []byte) (*ExtendedKey, error) {
+	mac := hmac.New(sha512.New, masterSecret)
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	key := new(big.Int).SetBytes(sum[:32])
+	if key.Sign() == 0 || key.Cmp(btcec.S256().N) >= 0 {
+		return nil, errors.New("wallet: the seed does not make a valid master key")
+	}
+	return &ExtendedKey{key: sum[:32], chainCode: sum[32:]}, nil
+}
+
+// Child derives the child key at the index, the hardened children cannot be derived from the public key
+func (extended *ExtendedKey) Child(index uint32) (*ExtendedKey, error) {
+	data := make([]byte, 0, 37)
+	if index >= Hardened {
+		data = append(append(data, 0), extended.key...)
+	} else {
+		_, public := btcec.PrivKeyFromBytes(btcec.S256(), extended.key)
+		data = append(data, public.SerializeCompressed()...)
+	}
+	data = binary.BigEndian.AppendUint32(data, index)
+
+	mac := hmac.New(sha512.New, extended.chainCode)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+
+	n := btcec.S256().N
+	tweak := new(big.Int).SetBytes(sum[:32])
+	if tweak.Cmp(n) >= 0 {
+		return nil, ErrInvalidChild
+	}
+	child := tweak.Add(tweak, new(big.Int).SetBytes(extended.key))
+	child.Mod(child, n)
+	if child.Sign() == 0 {
+		return nil, ErrInvalidChild
+	}
+
+	key := make([]byte, 32)
+	child.FillBytes(key)
+	return &ExtendedKey{key: key, chainCode: sum[32:]}, nil
+}
+
+// Derive derives the descendant at the path
+func (extended *ExtendedKey) Derive(path Path) (*ExtendedKey, error) {
+	key := extended
+	for _, index := range path {
+		child, err := key.Child(index)
+		if err != nil {
+			return nil, err
+		}
+		key = child
+	}
+	return key, nil
+}
+
+// PrivateKey is the secp256k1 key used to sign
+func (extended *ExtendedKey) PrivateKey() *protocol.PrivateKey {
+	return protocol.PrivateKeyFromBytes(extended.key)
+}
@@ -0,0 +1,85 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestMasterKeyDerivation(t *testing.T) {
+	// test vector 1 of BIP32
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	master, err := NewMasterKey(seed)
+	if err != nil {
+		t.Fatal("Error deriving the master key :", err)
+	}
+	expected := map[string]string{
+		"m/0'":                   "edb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea",
+		"m/0'/1":                 "3c6cb8d0f6a264c91ea8b5030fadaa8e538b020f0a387421a12de9319dc93368",
+		"m/0'/1/2'":              "cbce0d719ecf7431d88e6a89fa1483e02e35092af60c042b1df2ff59fa424dca",
+		"m/0'/1/2'/2":            "0f479245fb19a38a1954c5c7c0ebab2f9bdfd96a17563ef28a6a4b1a2a764ef4",
+		"m/0'/1/2h/2/1000000000": "471b76e389e528d6de6d816857e012c5455051cad6660850e58372a6c3e6e7c8",
+	}
+	for encoded, key := range expected {
+		path, err := ParsePath(encoded)
+		if err != nil {
+			t.Fatal("Error parsing the path :", err)
+		}
+		child, err := master.Derive(path)
+		if err != nil {
+			t.Fatal("Error deriving the key :", err)
+		}
+		if actual := hex.EncodeToString(child.PrivateKey().ToBytes()); actual != key {
+			t.Fatal(encoded, "expected :", key, "actual", actual)
+		}
+	}
+}
+
+func TestMnemonic(t *testing.T) {
+	// test vector of BIP39, the keys are checked against btcutil hdkeychain
+	hd, err := NewHD("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon  about", "TREZOR")
+	if err != nil {
+		t.Fatal("Error restoring the mnemonic :", err)
+	}
+	derivations, err := hd.Addresses(0, 2)
+	if err != nil || len(derivations) != 2 {
+		t.Fatal("Error listing the addresses :", err)
+	}
+	account, _ := hd.Account(1)
+	expected := map[string]string{
+		"m/44'/5394253'/0'/0/0": "440103a455d723fa5e8f82f0b80d363cc5b8a9f4d59ec902a5b8a3d4a61d330f",
+		"m/44'/5394253'/0'/0/1": "c3fab578045462fdb38638c6039ae6476f12e6abc928545c6dca8e358ed6c664",
+		"m/44'/5394253'/1'/0/0": "5f6ba227a1fec2bcbd62a7c3e64a91e6a857c0676377a047f9d0771b46f27022",
+	}
+	for _, derivation := range append(derivations, account) {
+		key := expected[derivation.Path.String()]
+		if actual := hex.EncodeToString(derivation.Privatekey.ToBytes()); actual != key {
+			t.Fatal(derivation.Path, "expected :", key, "actual", actual)
+		}
+		if derivation.Address.Encoded != derivation.Publickey.GetAddress().Encoded {
+			t.Fatal("the address should be the one of the derived key")
+		}
+	}
+
+	generated, err := NewMnemonic()
+	if err != nil {
+		t.Fatal("Error generating the mnemonic :", err)
+	}
+	if _, err := NewHD(generated, ""); err != nil {
+		t.Fatal("Error restoring the generated mnemonic :", err)
+	}
+	if _, err := NewHD("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon", ""); !errors.Is(err, ErrInvalidMnemonic) {
+		t.Fatal("expected :", ErrInvalidMnemonic, "actual", err)
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	for _, invalid := range []string{"", "0/1", "m/x", "m/2147483648", "m/1''"} {
+		if _, err := ParsePath(invalid); !errors.Is(err, ErrInvalidPath) {
+			t.Fatal(invalid, "expected :", ErrInvalidPath, "actual", err)
+		}
+	}
+	if path := AccountPath(3, 7).String(); path != "m/44'/5394253'/3'/0/7" {
+		t.Fatal("expected : m/44'/5394253'/3'/0/7 actual", path)
+	}
+}
@@ -0,0 +1,109 @@
+package wallet
+
+import (
+	"errors"
+	"log"
+	"republicofminer-client-go/vault"
+)
+
+// HDWallet derives the keys of many accounts from the seed of a mnemonic
+// the mnemonic is enough to rebuild every key, it should be written down
+type HDWallet struct {
+	Mnemonic string
+	master   *ExtendedKey
+}
+
+// Derivation is a wallet derived from the seed and its path
+type Derivation struct {
+	Path Path
+	*Wallet
+}
+
+// CreateHD generates a new mnemonic and saves it in the vault
+func CreateHD(vault *vault.Vault, passphrase string) (*HDWallet, error) {
+	mnemonic, err := NewMnemonic()
+	if err != nil {
+		return nil, err
+	}
+	return RestoreHD(vault, mnemonic, passphrase)
+}
+
+// RestoreHD checks the mnemonic written down and saves it in the vault
+func RestoreHD(vault *vault.Vault, mnemonic string, passphrase string) (*HDWallet, error) {
+	hd, err := NewHD(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := vault.Load(MnemonicItem); err == nil {
+		return nil, errors.New("wallet: the vault already has a mnemonic")
+	}
+	if err := vault.Save(MnemonicItem, []byte(hd.Mnemonic)); err != nil {
+		return nil, err
+	}
+	if err := vault.Save(PassphraseItem, []byte(passphrase)); err != nil {
+		return nil, err
+	}
+	return hd, nil
+}
+
+// LoadHD loads the mnemonic saved in the vault
+func LoadHD(vault *vault.Vault) (*HDWallet, error) {
+	mnemonic, err := vault.Load(MnemonicItem)
+	if err != nil {
+		return nil, err
+	}
+	// the passphrase is optional
+	passphrase, _ := vault.Load(PassphraseItem)
+	return NewHD(string(mnemonic), string(passphrase))
+}
+
+// NewHD derives the master key of the mnemonic without saving it
+func NewHD(mnemonic string, passphrase string) (*HDWallet, error) {
+	seed, err := Seed(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	master, err := NewMasterKey(seed)
+	if err != nil {
+		return nil, err
+	}
+	return &HDWallet{Mnemonic: mnemonic, master: master}, nil
+}
+
+// Derive derives the wallet at the path
+func (hd *HDWallet) Derive(path Path) (*Wallet, error) {
+	key, err := hd.master.Derive(path)
+	if err != nil {
+		return nil, err
+	}
+	return fromKey("", key.PrivateKey()), nil
+}
+
+// Account derives the first address of the account
+func (hd *HDWallet) Account(account uint32) (*Derivation, error) {
+	path := AccountPath(account, 0)
+	wallet, err := hd.Derive(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Derivation{Path: path, Wallet: wallet}, nil
+}
+
+// Addresses lists count addresses of the account with their path
+// the indexes with an invalid key are skipped as BIP32 recommends
+func (hd *HDWallet) Addresses(account uint32, count int) ([]*Derivation, error) {
+	derivations := []*Derivation{}
+	for index := uint32(0); len(derivations) < count && index < Hardened; index++ {
+		path := AccountPath(account, index)
+		wallet, err := hd.Derive(path)
+		if err == ErrInvalidChild {
+			log.Println("Skipped the invalid key", path)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		derivations = append(derivations, &Derivation{Path: path, Wallet: wallet})
+	}
+	return derivations, nil
+}
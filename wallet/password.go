@@ -0,0 +1,100 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+const (
+	// PasswordEnv is the environment variable holding the password of the vault
+	PasswordEnv = "ROM_VAULT_PASSWORD"
+	// PasswordFileEnv is the environment variable holding the path of the file that contains the password
+	PasswordFileEnv = "ROM_VAULT_PASSWORD_FILE"
+)
+
+// ErrPasswordMismatch is returned when the confirmation differs from the typed password
+var ErrPasswordMismatch = errors.New("wallet: the passwords do not match")
+
+// ErrNoPassword is returned when the password is not configured and cannot be asked
+var ErrNoPassword = errors.New("wallet: no vault password, set " + PasswordEnv + " or " + PasswordFileEnv + " or run in a terminal")
+
+// Password tells where the password of the vault comes from
+// the file comes first, then the environment variables, then the prompt
+type Password struct {
+	// File is the path of the file that contains the password, its trailing new line is ignored
+	File string
+	// NonInteractive disables the prompt, for the servers where nobody can type the password
+	NonInteractive bool
+	// Env and FileEnv replace PasswordEnv and PasswordFileEnv, Prompt replaces the question of the vault password
+	Env     string
+	FileEnv string
+	Prompt  string
+	// Confirm asks the typed password twice, a typo in a new password would leave the data unreadable
+	Confirm bool
+}
+
+// Get reads the password from the first configured source
+func (password Password) Get() (string, error) {
+	env, fileEnv, prompt := password.Env, password.FileEnv, password.Prompt
+	if env == "" {
+		env = PasswordEnv
+	}
+	if fileEnv == "" {
+		fileEnv = PasswordFileEnv
+	}
+	if prompt == "" {
+		prompt = "Vault password"
+	}
+
+	file := password.File
+	if file == "" {
+		file = os.Getenv(fileEnv)
+	}
+	if file != "" {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("wallet: cannot read the password file : %v", err)
+		}
+		return strings.TrimRight(string(content), "\r\n"), nil
+	}
+
+	if value, ok := os.LookupEnv(env); ok {
+		return value, nil
+	}
+
+	if password.NonInteractive || !term.IsTerminal(int(os.Stdin.Fd())) {
+		if env != PasswordEnv {
+			return "", fmt.Errorf("wallet: no password, set %s or %s or run in a terminal", env, fileEnv)
+		}
+		return "", ErrNoPassword
+	}
+	typed, err := read(prompt)
+	if err != nil {
+		return "", err
+	}
+	if password.Confirm {
+		confirmed, err := read(prompt + " again")
+		if err != nil {
+			return "", err
+		}
+		if confirmed != typed {
+			return "", ErrPasswordMismatch
+		}
+	}
+	return typed, nil
+}
+
+// read asks the password in the terminal without echo
+func read(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt, " : ")
+	typed, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	return string(typed), nil
+}
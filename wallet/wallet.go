@@ -1,36 +1,26 @@
 package wallet
 
 import (
-	"fmt"
-	"log"
 	"republicofminer-client-go/protocol"
-	"republicofminer-client-go/vault"
 )
 
-var Privatekey *protocol.PrivateKey
-var Publickey *protocol.PublicKey
-var Address *protocol.Address
+// Wallet holds the private key stored in the vault
+type Wallet struct {
+	// Name is the name of the account when the wallet is one of the Accounts
+	Name       string
+	Privatekey *protocol.PrivateKey
+	Publickey  *protocol.PublicKey
+	Address    *protocol.Address
+}
+
+// VaultName is the name of the default vault in its directory
+const VaultName = "republicofminer"
 
-func Load() {
-	vault.Unlock("republicofminer", "8dLyWpyupBty")
-	pk, err := vault.Load("wallet")
+// Sign signs the data with the private key of the wallet
+func (wallet *Wallet) Sign(data []byte) (*protocol.PublicKey, *protocol.Signature, error) {
+	signature, err := wallet.Privatekey.SignMessage(data, protocol.Network)
 	if err != nil {
-		err := vault.Save("wallet", Privatekey.ToBytes())
-		if err != nil {
-			fmt.Println("Save Failed", err)
-			return
-		}
-	} else {
-		Privatekey = protocol.PrivateKeyFromBytes(pk)
+		return nil, nil, err
 	}
-	Publickey = Privatekey.GetPublicKey()
-	Address = Publickey.GetAddress()
-
-	log.Println("Loaded wallet :", Address.Encoded)
-	// fmt.Println("Private key :", Privatekey.ToBase64())
-}
-
-func Sign(data []byte) (*protocol.PublicKey, *protocol.Signature) {
-	signature, _ := Privatekey.SignMessage(data, protocol.Network)
-	return Publickey, signature
+	return wallet.Publickey, signature, nil
 }
@@ -2,6 +2,7 @@ package web
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -10,28 +11,44 @@ import (
 	"republicofminer-client-go/explorer/api"
 	"republicofminer-client-go/protocol/converter/apitoprotocol"
 	"republicofminer-client-go/protocol/format/address32"
+	"republicofminer-client-go/verify"
 	"strconv"
 
 	"github.com/gorilla/mux"
 )
 
-func Run() {
-	go explorer.Connect()
+type server struct {
+	explorer *explorer.Explorer
+}
+
+// DefaultAddress is the address the web server listens on
+const DefaultAddress = ":3000"
+
+// Run starts the web server that answers with the data of the explorer
+func Run(explorer *explorer.Explorer) {
+	log.Fatal(Listen(explorer, DefaultAddress))
+}
+
+// Listen serves the data of the explorer on the address until the server fails
+func Listen(explorer *explorer.Explorer, address string) error {
+	server := &server{explorer: explorer}
 
 	router := mux.NewRouter()
 	router.UseEncodedPath()
 	router.StrictSlash(false)
-	router.HandleFunc(`/block/{id}`, handleblock).Methods("GET")
-	router.HandleFunc(`/tx/{hash}`, handletx).Methods("GET")
-	router.HandleFunc(`/account/{address}`, handleaccount).Methods("GET")
+	router.HandleFunc(`/block/{id}`, server.handleblock).Methods("GET")
+	router.HandleFunc(`/tx/{hash}`, server.handletx).Methods("GET")
+	router.HandleFunc(`/account/{address}`, server.handleaccount).Methods("GET")
+	router.HandleFunc(`/verify`, server.handleverify).Methods("POST")
 
 	// Start the server
-	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", 3000), router))
+	log.Println("Listening on", address)
+	return http.ListenAndServe(address, router)
 }
 
 var HASHLENGTH = len("L1gwhBkBNWOAS048Dv2P+jSmLZxymCaogpvVSrfTrZY=")
 
-func handleblock(writer http.ResponseWriter, request *http.Request) {
+func (server *server) handleblock(writer http.ResponseWriter, request *http.Request) {
 	params := mux.Vars(request)
 	id, ok := params["id"]
 	if !ok {
@@ -42,20 +59,25 @@ func handleblock(writer http.ResponseWriter, request *http.Request) {
 	var ledger *api.Ledger
 	height, err := strconv.ParseInt(id, 10, 64)
 	if err == nil {
-		ledger = explorer.GetLedgerByHeight(height)
-	} else if hash, err := url.QueryUnescape(id); err == nil && len(hash) == HASHLENGTH {
-		ledger = explorer.GetLedgerByHash(hash)
+		ledger, err = server.explorer.GetLedgerByHeight(height)
+	} else if hash, e := url.QueryUnescape(id); e == nil && len(hash) == HASHLENGTH {
+		ledger, err = server.explorer.GetLedgerByHash(hash)
 	} else {
 		http.Error(writer, "Error parsing the block id", http.StatusInternalServerError)
 		return
 	}
 
+	if err != nil {
+		fail(writer, err)
+		return
+	}
+
 	encoded, _ := json.Marshal(ledger)
 	// fmt.Println(encoded)
 	writer.Write(encoded)
 }
 
-func handletx(writer http.ResponseWriter, request *http.Request) {
+func (server *server) handletx(writer http.ResponseWriter, request *http.Request) {
 	params := mux.Vars(request)
 	hash, ok := params["hash"]
 	if !ok {
@@ -67,13 +89,22 @@ func handletx(writer http.ResponseWriter, request *http.Request) {
 
 	if err != nil {
 		http.Error(writer, "Error parsing the transaction hash", http.StatusInternalServerError)
+		return
 	}
 
-	tx := explorer.GetTransaction(hash)
+	tx, err := server.explorer.GetTransaction(hash)
+	if err != nil {
+		fail(writer, err)
+		return
+	}
 
 	// verify the transaction hash
-	t := apitoprotocol.ToTransaction(tx)
-	if t.Hash().ToBase64() != tx.Hash {
+	t, err := apitoprotocol.ToTransaction(tx)
+	if err != nil {
+		log.Println("The transaction cannot be converted :", err)
+	} else if h, err := t.Hash(); err != nil {
+		log.Println("The hash of the transaction cannot be verified :", err)
+	} else if h.ToBase64() != tx.Hash {
 		log.Println("The hash of the transaction does not match")
 	}
 
@@ -82,7 +113,7 @@ func handletx(writer http.ResponseWriter, request *http.Request) {
 	writer.Write(encoded)
 }
 
-func handleaccount(writer http.ResponseWriter, request *http.Request) {
+func (server *server) handleaccount(writer http.ResponseWriter, request *http.Request) {
 	params := mux.Vars(request)
 	address, ok := params["address"]
 	if !ok {
@@ -96,9 +127,53 @@ func handleaccount(writer http.ResponseWriter, request *http.Request) {
 		return
 	}
 
-	account := explorer.GetAccount(address)
+	account, err := server.explorer.GetAccount(address)
+	if err != nil {
+		fail(writer, err)
+		return
+	}
 
 	encoded, _ := json.Marshal(account)
 	// fmt.Println(encoded)
 	writer.Write(encoded)
 }
+
+// MaxVerifyBody is the maximum size of the transaction posted to /verify
+const MaxVerifyBody = 1 << 20
+
+// handleverify verifies the posted transaction and signatures, the body is a SendTransactionRequest
+func (server *server) handleverify(writer http.ResponseWriter, request *http.Request) {
+	var body api.SendTransactionRequest
+	err := json.NewDecoder(http.MaxBytesReader(writer, request.Body, MaxVerifyBody)).Decode(&body)
+	if err != nil {
+		http.Error(writer, "Error parsing the transaction", http.StatusBadRequest)
+		return
+	}
+
+	report, err := verify.Transaction(body.Transaction, body.Signatures)
+	if err != nil {
+		http.Error(writer, fmt.Sprint("Error verifying the transaction : ", err), http.StatusBadRequest)
+		return
+	}
+
+	encoded, _ := json.Marshal(report)
+	writer.Header().Set("Content-Type", "application/json")
+	writer.Write(encoded)
+}
+
+// fail writes the status matching the error returned by the explorer
+func fail(writer http.ResponseWriter, err error) {
+	log.Println("explorer request failed :", err)
+
+	var result *explorer.ServerError
+	switch {
+	case errors.Is(err, explorer.ErrNotFound):
+		http.Error(writer, "Not found", http.StatusNotFound)
+	case errors.Is(err, explorer.ErrTimeout):
+		http.Error(writer, "The explorer did not answer in time", http.StatusGatewayTimeout)
+	case errors.As(err, &result):
+		http.Error(writer, fmt.Sprintf("The explorer failed with result code %d", result.ResultCode), http.StatusBadGateway)
+	default:
+		http.Error(writer, "Error requesting the explorer", http.StatusBadGateway)
+	}
+}